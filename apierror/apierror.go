@@ -0,0 +1,135 @@
+// Package apierror defines the one shape every API error takes:
+//
+//	{"error": {"status": 404, "code": "NO_COPY_AVAILABLE", "message": "...", "details": {"book_type_id": 3}}}
+//
+// Handlers pass an *Error to Abort and the error handling middleware writes
+// it. Codes are stable so clients can branch on them; messages may change.
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Error struct {
+	Status  int            `json:"status"`
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Constructor function to create a new Error
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// With returns a copy carrying one more detail
+func (e *Error) With(key string, value any) *Error {
+	copied := *e
+	copied.Details = make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		copied.Details[k] = v
+	}
+	copied.Details[key] = value
+	return &copied
+}
+
+// WithMessage returns a copy with a more specific message
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// Abort hands err to the error handling middleware and skips the remaining handlers
+func Abort(c *gin.Context, err *Error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Internal is a server side failure; the cause is logged, never returned
+func Internal(message string) *Error {
+	return New(http.StatusInternalServerError, "INTERNAL", message)
+}
+
+// Request errors
+var (
+	InvalidPayload       = New(http.StatusUnprocessableEntity, "INVALID_PAYLOAD", "Invalid request payload")
+	InvalidID            = New(http.StatusBadRequest, "INVALID_ID", "ID in the path must be a positive integer")
+	InvalidCursor        = New(http.StatusBadRequest, "INVALID_CURSOR", "Cursor is malformed or belongs to another sort order")
+	EmptyRequest         = New(http.StatusBadRequest, "EMPTY_REQUEST", "No IDs provided")
+	InvalidISBN          = New(http.StatusUnprocessableEntity, "INVALID_ISBN", "Invalid ISBN")
+	InvalidEmail         = New(http.StatusUnprocessableEntity, "INVALID_EMAIL", "Invalid email address")
+	InvalidPhone         = New(http.StatusUnprocessableEntity, "INVALID_PHONE", "Invalid phone number")
+	InvalidBarcodes      = New(http.StatusUnprocessableEntity, "INVALID_BARCODES", "Barcodes must be unique and not blank")
+	BarcodeCountMismatch = New(http.StatusUnprocessableEntity, "BARCODE_COUNT_MISMATCH", "Barcodes must match the number of copies")
+	RouteNotFound        = New(http.StatusNotFound, "ROUTE_NOT_FOUND", "No such endpoint")
+)
+
+// Authentication and authorization errors
+var (
+	Unauthorized          = New(http.StatusUnauthorized, "UNAUTHORIZED", "Unauthorized")
+	TokenMissing          = New(http.StatusUnauthorized, "TOKEN_MISSING", "Authorization header is missing")
+	TokenInvalid          = New(http.StatusUnauthorized, "TOKEN_INVALID", "Invalid or expired token")
+	TokenExpired          = New(http.StatusUnauthorized, "TOKEN_EXPIRED", "Token expired")
+	SessionRevoked        = New(http.StatusUnauthorized, "SESSION_REVOKED", "Session has expired or been revoked")
+	InvalidCredentials    = New(http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid username or password")
+	WrongPassword         = New(http.StatusForbidden, "WRONG_PASSWORD", "Current password is incorrect")
+	RefreshTokenInvalid   = New(http.StatusUnauthorized, "REFRESH_TOKEN_INVALID", "Invalid refresh token")
+	RefreshTokenReused    = New(http.StatusUnauthorized, "REFRESH_TOKEN_REUSED", "Refresh token has already been used")
+	Forbidden             = New(http.StatusForbidden, "FORBIDDEN", "You are not allowed to access this resource")
+	SelfActionForbidden   = New(http.StatusForbidden, "SELF_ACTION_FORBIDDEN", "You are not allowed to do this to your own account")
+	UsernameTaken         = New(http.StatusConflict, "USERNAME_TAKEN", "Username already in use")
+	UserNotFound          = New(http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+	UsersHaveLoans        = New(http.StatusConflict, "USERS_HAVE_LOANS", "Some users still have books on loan")
+	PaymentExceedsBalance = New(http.StatusBadRequest, "PAYMENT_EXCEEDS_BALANCE", "Payment exceeds outstanding balance")
+)
+
+// Circulation errors
+var (
+	FinesBlocked        = New(http.StatusForbidden, "FINES_BLOCKED", "Outstanding fines exceed the allowed limit")
+	LoanLimitReached    = New(http.StatusForbidden, "LOAN_LIMIT_REACHED", "Loan limit reached")
+	RenewalLimitReached = New(http.StatusForbidden, "RENEWAL_LIMIT_REACHED", "Renewal limit reached")
+	RecordNotFound      = New(http.StatusNotFound, "RECORD_NOT_FOUND", "Record not found")
+	RecordNotOwned      = New(http.StatusForbidden, "RECORD_NOT_OWNED", "Record belongs to another user")
+	RecordClosed        = New(http.StatusForbidden, "RECORD_CLOSED", "Record is already closed")
+	LoanOverdue         = New(http.StatusForbidden, "LOAN_OVERDUE", "Loan is overdue")
+	NoCopyAvailable     = New(http.StatusNotFound, "NO_COPY_AVAILABLE", "No available copy of this book")
+	BookNotFound        = New(http.StatusNotFound, "BOOK_NOT_FOUND", "Book not found")
+	CopyNotFound        = New(http.StatusNotFound, "COPY_NOT_FOUND", "Book copy not found")
+	CopyTaken           = New(http.StatusConflict, "COPY_TAKEN", "Book copy is no longer available")
+	CopyCheckedOut      = New(http.StatusConflict, "COPY_CHECKED_OUT", "Book copy is checked out")
+	CopyOnHoldShelf     = New(http.StatusConflict, "COPY_ON_HOLD_SHELF", "Book copy is on the hold shelf")
+	CopyHeldForOther    = New(http.StatusConflict, "COPY_HELD_FOR_OTHER", "Book copy is held for another patron")
+	CopyWithdrawn       = New(http.StatusConflict, "COPY_WITHDRAWN", "Book copy is withdrawn")
+	CopyNotCheckedOut   = New(http.StatusConflict, "COPY_NOT_CHECKED_OUT", "Book copy is not checked out")
+	CopiesOnLoan        = New(http.StatusConflict, "COPIES_ON_LOAN", "Some copies are still rented out")
+	BarcodeTaken        = New(http.StatusConflict, "BARCODE_TAKEN", "Barcode already in use")
+	HoldNotFound        = New(http.StatusNotFound, "HOLD_NOT_FOUND", "Hold not found")
+	HoldNotOwned        = New(http.StatusForbidden, "HOLD_NOT_OWNED", "Hold belongs to another user")
+	HoldClosed          = New(http.StatusForbidden, "HOLD_CLOSED", "Hold is already closed")
+	HoldLimitReached    = New(http.StatusForbidden, "HOLD_LIMIT_REACHED", "Hold limit reached")
+	HoldExists          = New(http.StatusConflict, "HOLD_EXISTS", "You already have a hold on this book")
+	BookAvailable       = New(http.StatusConflict, "BOOK_AVAILABLE", "Book is available, borrow it instead")
+	FineNotFound        = New(http.StatusNotFound, "FINE_NOT_FOUND", "Fine not found")
+	FineSettled         = New(http.StatusConflict, "FINE_SETTLED", "Fine is already settled")
+	PolicyExists        = New(http.StatusConflict, "POLICY_EXISTS", "A policy already exists for this patron category and item type")
+	PolicyNotFound      = New(http.StatusNotFound, "POLICY_NOT_FOUND", "Loan policy not found")
+)
+
+// Health errors
+var (
+	ServerDraining      = New(http.StatusServiceUnavailable, "SERVER_DRAINING", "Server is shutting down")
+	DatabaseUnavailable = New(http.StatusServiceUnavailable, "DATABASE_UNAVAILABLE", "Database unavailable")
+)
+
+// Trash errors
+var (
+	NotInTrash    = New(http.StatusNotFound, "NOT_IN_TRASH", "Deleted row not found")
+	ParentDeleted = New(http.StatusConflict, "PARENT_DELETED", "Restore the owning user or title first")
+)
@@ -0,0 +1,300 @@
+// Package config loads the server settings once at startup. Values come from,
+// lowest precedence first, the defaults below, an optional YAML file, an
+// optional .env file and the process environment.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"library/logging"
+	"library/models"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Shown in place of secrets by Redacted
+const redacted = "[REDACTED]"
+
+type Config struct {
+	Port     string         `yaml:"port"`
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Auth     AuthConfig     `yaml:"auth"`
+	CORS     CORSConfig     `yaml:"cors"`
+	Fines    FineConfig     `yaml:"fines"`
+	Purge    PurgeConfig    `yaml:"purge"`
+	Log      LogConfig      `yaml:"log"`
+}
+
+type ServerConfig struct {
+	ReadTimeoutSeconds     int `yaml:"read_timeout_seconds"`
+	WriteTimeoutSeconds    int `yaml:"write_timeout_seconds"`
+	IdleTimeoutSeconds     int `yaml:"idle_timeout_seconds"`
+	ShutdownTimeoutSeconds int `yaml:"shutdown_timeout_seconds"` // how long in-flight requests get to finish
+	DrainDelaySeconds      int `yaml:"drain_delay_seconds"`      // how long /readyz fails before connections close
+}
+
+type DatabaseConfig struct {
+	Driver string `yaml:"driver"` // postgres or sqlite
+	URL    string `yaml:"url"`
+}
+
+type AuthConfig struct {
+	Secret             string `yaml:"secret"` // signs access tokens
+	AccessTokenMinutes int    `yaml:"access_token_minutes"`
+	SessionDays        int    `yaml:"session_days"`
+}
+
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allow_origins"`
+}
+
+type FineConfig struct {
+	DailyRate      int64 `yaml:"daily_rate"`
+	GraceDays      int   `yaml:"grace_days"`
+	MaxAmount      int64 `yaml:"max_amount"`      // 0 for no cap
+	BlockThreshold int64 `yaml:"block_threshold"` // balance above which borrowing stops
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"` // debug, info, warn or error
+}
+
+type PurgeConfig struct {
+	RetentionDays int `yaml:"retention_days"` // how long deleted rows stay in the trash
+	IntervalHours int `yaml:"interval_hours"`
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() Config {
+	return Config{
+		Port: "8000",
+		Server: ServerConfig{
+			ReadTimeoutSeconds:     15,
+			WriteTimeoutSeconds:    30,
+			IdleTimeoutSeconds:     60,
+			ShutdownTimeoutSeconds: 20,
+			DrainDelaySeconds:      5,
+		},
+		Database: DatabaseConfig{Driver: "postgres"},
+		Auth: AuthConfig{
+			AccessTokenMinutes: 15,
+			SessionDays:        30,
+		},
+		CORS: CORSConfig{AllowOrigins: []string{"http://localhost:3000"}},
+		Fines: FineConfig{
+			DailyRate:      25,
+			MaxAmount:      2000,
+			BlockThreshold: 1000,
+		},
+		Purge: PurgeConfig{RetentionDays: 90, IntervalHours: 24},
+		Log:   LogConfig{Level: "info"},
+	}
+}
+
+// Load reads the YAML file at path when given, then .env when present, then
+// the environment, and validates the result
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	// Containers get real environment variables and usually no .env file
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, fmt.Errorf("load .env: %w", err)
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+func (cfg *Config) applyEnv() error {
+	var errs []error
+	setString(&cfg.Port, "PORT")
+	errs = append(errs, setInt(&cfg.Server.ReadTimeoutSeconds, "READ_TIMEOUT_SECONDS"))
+	errs = append(errs, setInt(&cfg.Server.WriteTimeoutSeconds, "WRITE_TIMEOUT_SECONDS"))
+	errs = append(errs, setInt(&cfg.Server.IdleTimeoutSeconds, "IDLE_TIMEOUT_SECONDS"))
+	errs = append(errs, setInt(&cfg.Server.ShutdownTimeoutSeconds, "SHUTDOWN_TIMEOUT_SECONDS"))
+	errs = append(errs, setInt(&cfg.Server.DrainDelaySeconds, "DRAIN_DELAY_SECONDS"))
+	setString(&cfg.Database.Driver, "DB_DRIVER")
+	setString(&cfg.Database.URL, "DB_URL")
+	setString(&cfg.Auth.Secret, "SECRET")
+	errs = append(errs, setInt(&cfg.Auth.AccessTokenMinutes, "ACCESS_TOKEN_MINUTES"))
+	errs = append(errs, setInt(&cfg.Auth.SessionDays, "SESSION_DAYS"))
+	if value, ok := os.LookupEnv("CORS_ORIGINS"); ok {
+		cfg.CORS.AllowOrigins = splitList(value)
+	}
+	errs = append(errs, setInt64(&cfg.Fines.DailyRate, "FINE_DAILY_RATE"))
+	errs = append(errs, setInt(&cfg.Fines.GraceDays, "FINE_GRACE_DAYS"))
+	errs = append(errs, setInt64(&cfg.Fines.MaxAmount, "FINE_MAX_AMOUNT"))
+	errs = append(errs, setInt64(&cfg.Fines.BlockThreshold, "FINE_BLOCK_THRESHOLD"))
+	errs = append(errs, setInt(&cfg.Purge.RetentionDays, "PURGE_RETENTION_DAYS"))
+	errs = append(errs, setInt(&cfg.Purge.IntervalHours, "PURGE_INTERVAL_HOURS"))
+	setString(&cfg.Log.Level, "LOG_LEVEL")
+	return errors.Join(errs...)
+}
+
+// Validate reports every setting that is missing or out of range
+func (cfg Config) Validate() error {
+	var errs []error
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port %q is not a valid port", cfg.Port))
+	}
+	if cfg.Server.ReadTimeoutSeconds < 1 || cfg.Server.WriteTimeoutSeconds < 1 || cfg.Server.IdleTimeoutSeconds < 1 || cfg.Server.ShutdownTimeoutSeconds < 1 {
+		errs = append(errs, errors.New("server timeouts must be at least 1 second"))
+	}
+	if cfg.Server.DrainDelaySeconds < 0 {
+		errs = append(errs, errors.New("server.drain_delay_seconds must not be negative"))
+	}
+	if cfg.Database.Driver != "postgres" && cfg.Database.Driver != "sqlite" {
+		errs = append(errs, fmt.Errorf("database.driver %q must be postgres or sqlite", cfg.Database.Driver))
+	}
+	if cfg.Database.URL == "" {
+		errs = append(errs, errors.New("database.url (DB_URL) is required"))
+	}
+	if cfg.Auth.Secret == "" {
+		errs = append(errs, errors.New("auth.secret (SECRET) is required"))
+	}
+	if cfg.Auth.AccessTokenMinutes < 1 {
+		errs = append(errs, errors.New("auth.access_token_minutes must be at least 1"))
+	}
+	if cfg.Auth.SessionDays < 1 {
+		errs = append(errs, errors.New("auth.session_days must be at least 1"))
+	}
+	if cfg.Fines.DailyRate < 0 || cfg.Fines.GraceDays < 0 || cfg.Fines.MaxAmount < 0 || cfg.Fines.BlockThreshold < 0 {
+		errs = append(errs, errors.New("fines settings must not be negative"))
+	}
+	if cfg.Purge.RetentionDays < 1 {
+		errs = append(errs, errors.New("purge.retention_days must be at least 1"))
+	}
+	if cfg.Purge.IntervalHours < 1 {
+		errs = append(errs, errors.New("purge.interval_hours must be at least 1"))
+	}
+	if _, err := cfg.Log.SlogLevel(); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy that is safe to print or log
+func (cfg Config) Redacted() Config {
+	if cfg.Database.URL != "" {
+		cfg.Database.URL = redacted
+	}
+	if cfg.Auth.Secret != "" {
+		cfg.Auth.Secret = redacted
+	}
+	return cfg
+}
+
+// YAML renders the redacted config in the format Load reads
+func (cfg Config) YAML() (string, error) {
+	data, err := yaml.Marshal(cfg.Redacted())
+	return string(data), err
+}
+
+func (s ServerConfig) ReadTimeout() time.Duration {
+	return time.Duration(s.ReadTimeoutSeconds) * time.Second
+}
+
+func (s ServerConfig) WriteTimeout() time.Duration {
+	return time.Duration(s.WriteTimeoutSeconds) * time.Second
+}
+
+func (s ServerConfig) IdleTimeout() time.Duration {
+	return time.Duration(s.IdleTimeoutSeconds) * time.Second
+}
+
+func (s ServerConfig) ShutdownTimeout() time.Duration {
+	return time.Duration(s.ShutdownTimeoutSeconds) * time.Second
+}
+
+func (s ServerConfig) DrainDelay() time.Duration {
+	return time.Duration(s.DrainDelaySeconds) * time.Second
+}
+
+func (a AuthConfig) AccessTokenTTL() time.Duration {
+	return time.Duration(a.AccessTokenMinutes) * time.Minute
+}
+
+func (a AuthConfig) SessionTTL() time.Duration {
+	return time.Duration(a.SessionDays) * 24 * time.Hour
+}
+
+func (f FineConfig) Policy() models.FinePolicy {
+	return models.FinePolicy{
+		DailyRate:      f.DailyRate,
+		GraceDays:      f.GraceDays,
+		MaxAmount:      f.MaxAmount,
+		BlockThreshold: f.BlockThreshold,
+	}
+}
+
+func (p PurgeConfig) Retention() time.Duration {
+	return time.Duration(p.RetentionDays) * 24 * time.Hour
+}
+
+func (p PurgeConfig) Interval() time.Duration {
+	return time.Duration(p.IntervalHours) * time.Hour
+}
+
+func (l LogConfig) SlogLevel() (slog.Level, error) {
+	return logging.ParseLevel(l.Level)
+}
+
+func setString(dest *string, key string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*dest = value
+	}
+}
+
+func setInt(dest *int, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s=%q is not a number", key, value)
+	}
+	*dest = parsed
+	return nil
+}
+
+func setInt64(dest *int64, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s=%q is not a number", key, value)
+	}
+	*dest = parsed
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
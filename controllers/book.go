@@ -1,52 +1,59 @@
 package controllers
 
 import (
+	"errors"
+	"library/apierror"
+	"library/logging"
+	"library/metrics"
 	"library/models"
-	"log"
+	"library/repositories"
+	"library/services"
+	"log/slog"
 	"net/http"
-	"sort"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-// Define a struct to hold the database instance
+// Define a struct to hold the repositories and services
 type BookController struct {
-	DB *gorm.DB
+	Books       repositories.BookRepository
+	Records     repositories.RecordRepository
+	Holds       repositories.HoldRepository
+	Circulation *services.CirculationService
+	Log         *slog.Logger
+	Metrics     *metrics.Metrics
 }
 
 // Constructor function to create a new BookController
-func NewBookController(db *gorm.DB) *BookController {
-	return &BookController{DB: db}
+func NewBookController(store repositories.Store, circulation *services.CirculationService, logger *slog.Logger, m *metrics.Metrics) *BookController {
+	return &BookController{Books: store.Books(), Records: store.Records(), Holds: store.Holds(), Circulation: circulation, Log: logger, Metrics: m}
 }
 
 func (bc *BookController) GetBookList(c *gin.Context) {
-	var bookTypes []models.BookType
 	var bookRequest models.BookRequest
 
-	// Bind JSON and return 422 Unprocessable Entity on failure
-	if err := c.ShouldBindJSON(&bookRequest); err != nil {
-		log.Printf("Invalid request payload: %v\n", err)
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request payload"})
+	// Bind the query or JSON body and return 422 Unprocessable Entity on failure
+	if err := bindSearch(c, &bookRequest); err != nil {
+		bc.Log.WarnContext(c, "Invalid request payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
 		return
 	}
 
-	var query = bc.DB.Offset(bookRequest.Page * bookRequest.PageSize).Limit(bookRequest.PageSize)
-	if bookRequest.Title != "" {
-		query = query.Where("title ilike ?", "%"+bookRequest.Title+"%")
-	}
-
 	// Fetch book types and return 500 Internal Server Error on failure
-	if err := query.Find(&bookTypes).Error; err != nil {
-		log.Printf("Database error fetching book list: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch book list"})
+	bookTypes, page, err := bc.Books.Search(bookRequest)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		apierror.Abort(c, apierror.InvalidCursor)
+		return
+	}
+	if err != nil {
+		bc.Log.ErrorContext(c, "Database error fetching book list", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch book list"))
 		return
 	}
 
+	// Nothing matching is an empty page, not an error
 	if len(bookTypes) == 0 {
-		log.Printf("No bookType found with search: %s", bookRequest.Title)
-		c.JSON(http.StatusOK, gin.H{"error": "No books found"})
+		c.JSON(http.StatusOK, gin.H{"books": []models.BookResponse{}, "total": page.Total, "next_cursor": page.NextCursor, "has_more": page.HasMore})
 		return
 	}
 
@@ -55,165 +62,111 @@ func (bc *BookController) GetBookList(c *gin.Context) {
 		bookTypeIDs = append(bookTypeIDs, bookType.ID)
 	}
 
-	var totalCounts []struct {
-		BookTypeID uint
-		Count      int
+	totalCounts, availableCounts, err := bc.Books.CopyCounts(bookTypeIDs)
+	if err != nil {
+		bc.Log.ErrorContext(c, "Error fetching book counts", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch total book count"))
+		return
 	}
-	if err := bc.DB.Model(&models.Book{}).
-		Select("book_type_id, COUNT(*) as count").
-		Where("book_type_id IN ?", bookTypeIDs).
-		Group("book_type_id").
-		Scan(&totalCounts).Error; err != nil {
-		log.Printf("Error fetching total book count: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch total book count"})
+
+	// Prepare response
+	booksResponse := PrepareBookResponses(bookTypes, totalCounts, availableCounts)
+	c.JSON(http.StatusOK, gin.H{"books": booksResponse, "total": page.Total, "next_cursor": page.NextCursor, "has_more": page.HasMore})
+}
+
+func (bc *BookController) GetBook(c *gin.Context) {
+	id, err := pathID(c)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidID)
 		return
 	}
 
-	var availableCounts []struct {
-		BookTypeID uint
-		Count      int
+	bookType, err := bc.Books.FindBookTypeDetails(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		apierror.Abort(c, apierror.BookNotFound.With("book_type_id", id))
+		return
 	}
-	if err := bc.DB.Model(&models.Book{}).
-		Select("book_type_id, COUNT(*) as count").
-		Where("book_type_id IN ? AND status = 1", bookTypeIDs).
-		Group("book_type_id").
-		Scan(&availableCounts).Error; err != nil {
-		log.Printf("Error fetching available book count: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch available book count"})
+	if err != nil {
+		bc.Log.ErrorContext(c, "Error fetching book type", "book_type_id", id, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch book"))
 		return
 	}
 
-	// Prepare response
-	booksResponse := PrepareBookResponses(bookTypes, totalCounts, availableCounts)
-	var count int64
-	bc.DB.Model(&models.BookType{}).Count(&count)
-	c.JSON(http.StatusOK, gin.H{"books": booksResponse, "total": count})
+	copies, err := bc.Books.Copies(id)
+	if err != nil {
+		bc.Log.ErrorContext(c, "Error fetching book copies", "book_type_id", id, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch book copies"))
+		return
+	}
+	bookIDs := make([]uint, len(copies))
+	for i, book := range copies {
+		bookIDs[i] = book.ID
+	}
+	dueDates, err := bc.Records.OpenDueDates(bookIDs)
+	if err != nil {
+		bc.Log.ErrorContext(c, "Error fetching due dates", "book_type_id", id, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch due dates"))
+		return
+	}
+	queue, err := bc.Holds.CountWaiting(id)
+	if err != nil {
+		bc.Log.ErrorContext(c, "Error counting holds", "book_type_id", id, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch hold queue"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"book": bookType.ToDetailResponse(copies, dueDates, queue)})
 }
 
 func (bc *BookController) BorrowBooks(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		log.Println("Unauthorized access attempt")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		bc.Log.WarnContext(c, "Unauthorized access attempt")
+		apierror.Abort(c, apierror.Unauthorized)
 		return
 	}
 	userData, ok := user.(models.UserResponse)
 	if !ok {
-		log.Println("Invalid user data in request")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user data"})
+		bc.Log.WarnContext(c, "Invalid user data in request")
+		apierror.Abort(c, apierror.Unauthorized)
 		return
 	}
-	userID := userData.ID
 
 	var bookTypeIDs models.BookIDsPayload
 	if err := c.ShouldBindJSON(&bookTypeIDs); err != nil {
-		log.Printf("Invalid borrow request payload: %v\n", err)
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request payload"})
+		bc.Log.WarnContext(c, "Invalid borrow request payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
 		return
 	}
 
 	if len(bookTypeIDs.BookTypeIDs) == 0 {
-		log.Println("Empty book borrow request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No book IDs provided"})
+		bc.Log.WarnContext(c, "Empty book borrow request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No book IDs provided"))
 		return
 	}
 
-	// Begin transaction
-	tx := bc.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			log.Println("Transaction panic, rolled back")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction failed"})
-		}
-	}()
-
-	var bookIDs []uint
-	var records []models.Record
-
-	for _, bookTypeID := range bookTypeIDs.BookTypeIDs {
-		var book models.Book
-
-		// Fetch an available book
-		if err := tx.Where("book_type_id = ? AND status = 1", bookTypeID).
-			First(&book).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				tx.Rollback()
-				log.Printf("No available books for book_type_id: %d\n", bookTypeID)
-				c.JSON(http.StatusNotFound, gin.H{"error": "No available books found for book_type_id", "book_type_id": bookTypeID})
-				return
-			}
-			tx.Rollback()
-			log.Printf("Error fetching available book: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch available book"})
-			return
+	records, err := bc.Circulation.Borrow(c, userData.ID, bookTypeIDs.BookTypeIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrNoCopyAvailable) {
+			bc.Metrics.NoCopyAvailable()
 		}
-
-		// Append to borrow list
-		bookIDs = append(bookIDs, book.ID)
-		records = append(records, models.Record{
-			UserID: userID,
-			BookID: book.ID,
-			DueAt:  time.Now().AddDate(0, 0, 28), // 4 weeks
-		})
-	}
-
-	// Update book status
-	if err := tx.Model(&models.Book{}).
-		Where("id IN ?", bookIDs).
-		Update("status", 2).Error; err != nil {
-		tx.Rollback()
-		log.Printf("Error updating book status: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book status"})
-		return
-	}
-
-	// Create borrowing records
-	if err := tx.Create(&records).Error; err != nil {
-		tx.Rollback()
-		log.Printf("Error creating borrow records: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create borrow records"})
-		return
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		log.Printf("Transaction commit failed: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		respondRuleError(c, bc.Log, err, "Failed to create borrow records")
 		return
 	}
+	bc.Metrics.Borrowed(metrics.ChannelSelf, len(records))
 
 	// Return response
-	log.Printf("User %d successfully borrowed %d books\n", userData.ID, len(records))
-	c.JSON(http.StatusOK, gin.H{"message": "Books borrowed successfully", "data": records})
+	logging.Event(c, bc.Log, logging.EventBorrow, "Books borrowed",
+		logging.IDs("book_type_ids", bookTypeIDs.BookTypeIDs), logging.IDs("record_ids", services.RecordIDs(records)), slog.Int("count", len(records)))
+	c.JSON(http.StatusOK, gin.H{"message": "Books borrowed successfully", "data": models.ToLoanResponses(records)})
 }
 
-// Prepare book responses
-func PrepareBookResponses(bookTypes []models.BookType, totalCounts, availableCounts []struct {
-	BookTypeID uint
-	Count      int
-}) []models.BookResponse {
-	// Convert counts into maps for fast lookup
-	totalMap := make(map[uint]int)
-	availableMap := make(map[uint]int)
-
-	for _, item := range totalCounts {
-		totalMap[item.BookTypeID] = item.Count
-	}
-	for _, item := range availableCounts {
-		availableMap[item.BookTypeID] = item.Count
-	}
-
+// Prepare book responses in the order the titles were fetched
+func PrepareBookResponses(bookTypes []models.BookType, totalCounts, availableCounts map[uint]int) []models.BookResponse {
 	// Generate response using ToResponse() method
-	var booksResponse []models.BookResponse
+	booksResponse := make([]models.BookResponse, 0, len(bookTypes))
 	for _, bookType := range bookTypes {
-		booksResponse = append(booksResponse, bookType.ToResponse(totalMap[bookType.ID], availableMap[bookType.ID]))
+		booksResponse = append(booksResponse, bookType.ToResponse(totalCounts[bookType.ID], availableCounts[bookType.ID]))
 	}
 
-	// Sort responses by ID
-	sort.Slice(booksResponse, func(i, j int) bool {
-		return booksResponse[i].ID < booksResponse[j].ID
-	})
-
 	return booksResponse
 }
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"library/apierror"
+	"library/models"
+	"library/repositories"
+	"library/services"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Define a struct to hold the store
+type CatalogController struct {
+	Store repositories.Store
+	Log   *slog.Logger
+}
+
+// Constructor function to create a new CatalogController
+func NewCatalogController(store repositories.Store, logger *slog.Logger) *CatalogController {
+	return &CatalogController{Store: store, Log: logger}
+}
+
+func (cc *CatalogController) CreateBookType(c *gin.Context) {
+	var bookTypePayload models.BookTypePayload
+	if err := c.ShouldBindJSON(&bookTypePayload); err != nil {
+		cc.Log.WarnContext(c, "Invalid create book request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	isbn10, isbn13, ok := normalizeISBNs(bookTypePayload.ISBN10, bookTypePayload.ISBN13)
+	if !ok {
+		cc.Log.WarnContext(c, "Invalid ISBN in create book request", "isbn10", bookTypePayload.ISBN10, "isbn13", bookTypePayload.ISBN13)
+		apierror.Abort(c, apierror.InvalidISBN)
+		return
+	}
+
+	bookType := models.BookType{
+		Title:           bookTypePayload.Title,
+		ISBN10:          isbn10,
+		ISBN13:          isbn13,
+		Publisher:       bookTypePayload.Publisher,
+		PublicationYear: bookTypePayload.PublicationYear,
+		Language:        strings.ToLower(bookTypePayload.Language),
+		PageCount:       bookTypePayload.PageCount,
+		Description:     bookTypePayload.Description,
+		ItemType:        bookTypePayload.ItemType,
+	}
+
+	if err := cc.Store.Transaction(func(store repositories.Store) error {
+		var err error
+		bookType, err = services.CreateBookType(c, cc.Log, store, bookType, bookTypePayload.Authors, bookTypePayload.Subjects, bookTypePayload.Copies, time.Now())
+		return err
+	}); err != nil {
+		respondRuleError(c, cc.Log, err, "Failed to create book")
+		return
+	}
+
+	cc.Log.InfoContext(c, "Book type created", "book_type_id", bookType.ID, "copies", bookTypePayload.Copies)
+	c.JSON(http.StatusCreated, gin.H{"message": "Book created successfully", "data": bookType})
+}
+
+func (cc *CatalogController) UpdateBookType(c *gin.Context) {
+	var bookTypePayload models.BookTypePayload
+	if err := c.ShouldBindJSON(&bookTypePayload); err != nil {
+		cc.Log.WarnContext(c, "Invalid update book request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if bookTypePayload.ID == 0 {
+		cc.Log.WarnContext(c, "Update book request without ID")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No book ID provided"))
+		return
+	}
+
+	isbn10, isbn13, ok := normalizeISBNs(bookTypePayload.ISBN10, bookTypePayload.ISBN13)
+	if !ok {
+		cc.Log.WarnContext(c, "Invalid ISBN in update book request", "isbn10", bookTypePayload.ISBN10, "isbn13", bookTypePayload.ISBN13)
+		apierror.Abort(c, apierror.InvalidISBN)
+		return
+	}
+
+	bookType := models.BookType{
+		ID:              bookTypePayload.ID,
+		Title:           bookTypePayload.Title,
+		ISBN10:          isbn10,
+		ISBN13:          isbn13,
+		Publisher:       bookTypePayload.Publisher,
+		PublicationYear: bookTypePayload.PublicationYear,
+		Language:        strings.ToLower(bookTypePayload.Language),
+		PageCount:       bookTypePayload.PageCount,
+		Description:     bookTypePayload.Description,
+		ItemType:        bookTypePayload.ItemType,
+	}
+
+	if err := cc.Store.Transaction(func(store repositories.Store) error {
+		var err error
+		bookType, err = services.UpdateBookType(store, bookType, bookTypePayload.Authors, bookTypePayload.Subjects)
+		return err
+	}); err != nil {
+		respondRuleError(c, cc.Log, err, "Failed to update book")
+		return
+	}
+
+	cc.Log.InfoContext(c, "Book type updated", "book_type_id", bookType.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Book updated successfully", "data": bookType})
+}
+
+func (cc *CatalogController) DeleteBookTypes(c *gin.Context) {
+	var bookTypeIDs models.BookIDsPayload
+	if err := c.ShouldBindJSON(&bookTypeIDs); err != nil {
+		cc.Log.WarnContext(c, "Invalid delete book request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if len(bookTypeIDs.BookTypeIDs) == 0 {
+		cc.Log.WarnContext(c, "Empty delete book request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No book IDs provided"))
+		return
+	}
+
+	if err := cc.Store.Transaction(func(store repositories.Store) error {
+		return services.DeleteBookTypes(store, bookTypeIDs.BookTypeIDs, time.Now())
+	}); err != nil {
+		respondRuleError(c, cc.Log, err, "Failed to delete books")
+		return
+	}
+
+	cc.Log.InfoContext(c, "Deleted book types", "book_type_ids", bookTypeIDs.BookTypeIDs)
+	c.JSON(http.StatusOK, gin.H{"message": "Books deleted successfully"})
+}
+
+func (cc *CatalogController) AddCopies(c *gin.Context) {
+	var bookCopiesPayload models.BookCopiesPayload
+	if err := c.ShouldBindJSON(&bookCopiesPayload); err != nil {
+		cc.Log.WarnContext(c, "Invalid add copies request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	barcodes := make([]string, len(bookCopiesPayload.Barcodes))
+	seen := make(map[string]bool)
+	for i, barcode := range bookCopiesPayload.Barcodes {
+		barcodes[i] = strings.TrimSpace(barcode)
+		if barcodes[i] == "" || seen[barcodes[i]] {
+			cc.Log.WarnContext(c, "Blank or repeated barcode in add copies request", "barcode", barcode)
+			apierror.Abort(c, apierror.InvalidBarcodes)
+			return
+		}
+		seen[barcodes[i]] = true
+	}
+	if len(barcodes) > 0 && len(barcodes) != bookCopiesPayload.Count {
+		cc.Log.WarnContext(c, "Add copies request with wrong number of barcodes", "barcodes", len(barcodes), "count", bookCopiesPayload.Count)
+		apierror.Abort(c, apierror.BarcodeCountMismatch)
+		return
+	}
+
+	var books []models.Book
+	if err := cc.Store.Transaction(func(store repositories.Store) error {
+		var err error
+		books, err = services.AddCopies(c, cc.Log, store, bookCopiesPayload.BookTypeID, bookCopiesPayload.Count, barcodes,
+			strings.TrimSpace(bookCopiesPayload.ShelfLocation), time.Now())
+		return err
+	}); err != nil {
+		respondRuleError(c, cc.Log, err, "Failed to create book copies")
+		return
+	}
+
+	cc.Log.InfoContext(c, "Added copies", "book_type_id", bookCopiesPayload.BookTypeID, "count", len(books))
+	c.JSON(http.StatusCreated, gin.H{"message": "Copies added successfully", "data": books})
+}
+
+func (cc *CatalogController) WithdrawCopy(c *gin.Context) {
+	var bookCopyPayload models.BookCopyPayload
+	if err := c.ShouldBindJSON(&bookCopyPayload); err != nil {
+		cc.Log.WarnContext(c, "Invalid withdraw copy request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	if err := services.WithdrawCopy(cc.Store, bookCopyPayload.ID, time.Now()); err != nil {
+		respondRuleError(c, cc.Log, err, "Failed to withdraw book copy")
+		return
+	}
+
+	cc.Log.InfoContext(c, "Book copy withdrawn", "book_id", bookCopyPayload.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Book copy withdrawn successfully"})
+}
+
+func (cc *CatalogController) MoveCopy(c *gin.Context) {
+	var bookCopyMovePayload models.BookCopyMovePayload
+	if err := c.ShouldBindJSON(&bookCopyMovePayload); err != nil {
+		cc.Log.WarnContext(c, "Invalid move copy request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	shelfLocation := strings.TrimSpace(bookCopyMovePayload.ShelfLocation)
+	if err := services.MoveCopy(cc.Store, bookCopyMovePayload.ID, shelfLocation); err != nil {
+		respondRuleError(c, cc.Log, err, "Failed to move book copy")
+		return
+	}
+
+	cc.Log.InfoContext(c, "Book copy moved", "book_id", bookCopyMovePayload.ID, "shelf_location", shelfLocation)
+	c.JSON(http.StatusOK, gin.H{"message": "Book copy moved successfully"})
+}
+
+// normalizeISBNs validates both ISBNs and fills in the ISBN-13 from the ISBN-10 when missing
+func normalizeISBNs(isbn10, isbn13 string) (string, string, bool) {
+	isbn10 = models.NormalizeISBN(isbn10)
+	isbn13 = models.NormalizeISBN(isbn13)
+	if isbn10 != "" && !models.ValidISBN10(isbn10) {
+		return "", "", false
+	}
+	if isbn13 != "" && !models.ValidISBN13(isbn13) {
+		return "", "", false
+	}
+	if isbn10 != "" && isbn13 == "" {
+		isbn13 = models.ISBN10To13(isbn10)
+	}
+	return isbn10, isbn13, true
+}
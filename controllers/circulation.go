@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"library/apierror"
+	"library/logging"
+	"library/metrics"
+	"library/models"
+	"library/services"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Define a struct to hold the circulation service
+type CirculationController struct {
+	Circulation *services.CirculationService
+	Log         *slog.Logger
+	Metrics     *metrics.Metrics
+}
+
+// Constructor function to create a new CirculationController
+func NewCirculationController(circulation *services.CirculationService, logger *slog.Logger, m *metrics.Metrics) *CirculationController {
+	return &CirculationController{Circulation: circulation, Log: logger, Metrics: m}
+}
+
+func (cc *CirculationController) CheckOut(c *gin.Context) {
+	var checkOutPayload models.CheckOutPayload
+	if err := c.ShouldBindJSON(&checkOutPayload); err != nil {
+		cc.Log.WarnContext(c, "Invalid check out payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	record, err := cc.Circulation.CheckOut(c, checkOutPayload.UserID, checkOutPayload.Barcode)
+	if err != nil {
+		respondRuleError(c, cc.Log, err, "Failed to check out book")
+		return
+	}
+	cc.Metrics.Borrowed(metrics.ChannelDesk, 1)
+
+	logging.Event(c, cc.Log, logging.EventCheckout, "Book checked out",
+		slog.Uint64("book_id", uint64(record.BookID)), slog.Uint64("record_id", uint64(record.ID)), slog.Uint64("patron_id", uint64(record.UserID)))
+	c.JSON(http.StatusOK, gin.H{"message": "Book checked out successfully", "data": record.ToLoanResponse()})
+}
+
+func (cc *CirculationController) CheckIn(c *gin.Context) {
+	var checkInPayload models.CheckInPayload
+	if err := c.ShouldBindJSON(&checkInPayload); err != nil {
+		cc.Log.WarnContext(c, "Invalid check in payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	record, err := cc.Circulation.CheckIn(c, checkInPayload.Barcode)
+	if err != nil {
+		respondRuleError(c, cc.Log, err, "Failed to return records")
+		return
+	}
+	cc.Metrics.Returned(metrics.ChannelDesk, 1)
+
+	logging.Event(c, cc.Log, logging.EventCheckin, "Book checked in",
+		slog.Uint64("book_id", uint64(record.BookID)), slog.Uint64("record_id", uint64(record.ID)), slog.Uint64("patron_id", uint64(record.UserID)))
+	c.JSON(http.StatusOK, gin.H{"message": "Book checked in successfully", "data": record.ToLoanResponse()})
+}
+
+// ruleErrors gives the API error for each rule the services enforce
+var ruleErrors = []struct {
+	rule error
+	err  *apierror.Error
+}{
+	{services.ErrFinesBlocked, apierror.FinesBlocked},
+	{services.ErrLoanLimitReached, apierror.LoanLimitReached},
+	{services.ErrRenewalLimitReached, apierror.RenewalLimitReached},
+	{services.ErrRecordNotFound, apierror.RecordNotFound},
+	{services.ErrNotOwner, apierror.RecordNotOwned},
+	{services.ErrRecordClosed, apierror.RecordClosed},
+	{services.ErrOverdue, apierror.LoanOverdue},
+	{services.ErrNoCopyAvailable, apierror.NoCopyAvailable},
+	{services.ErrUserNotFound, apierror.UserNotFound},
+	{services.ErrCopyNotFound, apierror.CopyNotFound},
+	{services.ErrCopyTaken, apierror.CopyTaken},
+	{services.ErrCopyCheckedOut, apierror.CopyCheckedOut},
+	{services.ErrCopyHeldForOther, apierror.CopyHeldForOther},
+	{services.ErrCopyWithdrawn, apierror.CopyWithdrawn},
+	{services.ErrNotCheckedOut, apierror.CopyNotCheckedOut},
+	{services.ErrCopyOnHoldShelf, apierror.CopyOnHoldShelf},
+	{services.ErrBookNotFound, apierror.BookNotFound},
+	{services.ErrBookAvailable, apierror.BookAvailable},
+	{services.ErrHoldExists, apierror.HoldExists},
+	{services.ErrHoldLimitReached, apierror.HoldLimitReached},
+	{services.ErrHoldNotFound, apierror.HoldNotFound},
+	{services.ErrHoldNotOwned, apierror.HoldNotOwned},
+	{services.ErrHoldClosed, apierror.HoldClosed},
+	{services.ErrFineNotFound, apierror.FineNotFound},
+	{services.ErrFineSettled, apierror.FineSettled},
+	{services.ErrPaymentExceedsBalance, apierror.PaymentExceedsBalance},
+	{services.ErrCopiesOnLoan, apierror.CopiesOnLoan},
+	{services.ErrBarcodeTaken, apierror.BarcodeTaken},
+	{services.ErrPolicyExists, apierror.PolicyExists},
+	{services.ErrPolicyNotFound, apierror.PolicyNotFound},
+}
+
+// respondRuleError answers with the code of the rule that refused the
+// request and the record, hold, fine, title or balance it concerns; anything
+// else is a failure reported as failed
+func respondRuleError(c *gin.Context, logger *slog.Logger, err error, failed string) {
+	for _, mapping := range ruleErrors {
+		if !errors.Is(err, mapping.rule) {
+			continue
+		}
+		apiErr := mapping.err
+		detail := services.Detail(err)
+		if detail.RecordID != 0 {
+			apiErr = apiErr.With("record_id", detail.RecordID)
+		}
+		if detail.HoldID != 0 {
+			apiErr = apiErr.With("hold_id", detail.HoldID)
+		}
+		if detail.FineID != 0 {
+			apiErr = apiErr.With("fine_id", detail.FineID)
+		}
+		if detail.BookTypeID != 0 {
+			apiErr = apiErr.With("book_type_id", detail.BookTypeID)
+		}
+		if detail.Balance != 0 {
+			apiErr = apiErr.With("balance", detail.Balance)
+		}
+		logger.WarnContext(c, "Request refused", "error", err)
+		apierror.Abort(c, apiErr)
+		return
+	}
+	logger.ErrorContext(c, failed, "error", err)
+	apierror.Abort(c, apierror.Internal(failed))
+}
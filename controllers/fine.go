@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"library/apierror"
+	"library/logging"
+	"library/models"
+	"library/repositories"
+	"library/services"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Define a struct to hold the store
+type FineController struct {
+	Store repositories.Store
+	Log   *slog.Logger
+}
+
+// Constructor function to create a new FineController
+func NewFineController(store repositories.Store, logger *slog.Logger) *FineController {
+	return &FineController{Store: store, Log: logger}
+}
+
+func (fc *FineController) GetFineList(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		fc.Log.WarnContext(c, "Unauthorized access attempt")
+		apierror.Abort(c, apierror.Unauthorized)
+		return
+	}
+	userData, _ := user.(models.UserResponse)
+
+	var fineSearchRequest models.FineSearchRequest
+	if err := c.ShouldBindJSON(&fineSearchRequest); err != nil {
+		fc.Log.WarnContext(c, "Invalid fine search request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	var statuses []uint
+	switch fineSearchRequest.Status {
+	case 1:
+		statuses = []uint{1}
+	case 2:
+		statuses = []uint{2, 3}
+	}
+
+	fines, total, err := fc.Store.Fines().List(userData.ID, statuses, fineSearchRequest.Pagination)
+	if err != nil {
+		fc.Log.ErrorContext(c, "Failed to fetch fines", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch fines"))
+		return
+	}
+
+	finesResponse := make([]models.FineResponse, len(fines))
+	for i, fine := range fines {
+		finesResponse[i] = fine.ToResponse()
+	}
+
+	balance, err := fc.Store.Fines().OutstandingBalance(userData.ID)
+	if err != nil {
+		fc.Log.ErrorContext(c, "Failed to fetch fine balance", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch fine balance"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"fines": finesResponse, "total": total, "balance": balance})
+}
+
+func (fc *FineController) PayFines(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		fc.Log.WarnContext(c, "Unauthorized access attempt")
+		apierror.Abort(c, apierror.Unauthorized)
+		return
+	}
+	staffData, _ := user.(models.UserResponse)
+
+	var paymentRequest models.FinePaymentRequest
+	if err := c.ShouldBindJSON(&paymentRequest); err != nil {
+		fc.Log.WarnContext(c, "Invalid fine payment payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	var payment models.FinePayment
+	var balance int64
+	if err := fc.Store.Transaction(func(store repositories.Store) error {
+		var err error
+		payment, balance, err = services.PayFines(store, paymentRequest.UserID, paymentRequest.Amount, staffData.ID, paymentRequest.Note)
+		return err
+	}); err != nil {
+		respondRuleError(c, fc.Log, err, "Failed to record payment")
+		return
+	}
+
+	logging.Event(c, fc.Log, logging.EventFinePaid, "Fine payment recorded",
+		slog.Uint64("payment_id", uint64(payment.ID)), slog.Uint64("patron_id", uint64(payment.UserID)), slog.Int64("amount", payment.Amount))
+	c.JSON(http.StatusOK, gin.H{"message": "Payment recorded successfully", "data": payment, "balance": balance})
+}
+
+func (fc *FineController) WaiveFines(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		fc.Log.WarnContext(c, "Unauthorized access attempt")
+		apierror.Abort(c, apierror.Unauthorized)
+		return
+	}
+	staffData, _ := user.(models.UserResponse)
+
+	var waiveRequest models.FineWaiveRequest
+	if err := c.ShouldBindJSON(&waiveRequest); err != nil {
+		fc.Log.WarnContext(c, "Invalid fine waive payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if len(waiveRequest.IDs) == 0 {
+		fc.Log.WarnContext(c, "Empty fine waive request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No fine IDs provided"))
+		return
+	}
+
+	var fines []models.Fine
+	if err := fc.Store.Transaction(func(store repositories.Store) error {
+		var err error
+		fines, err = services.WaiveFines(store, waiveRequest.IDs, staffData.ID, waiveRequest.Note)
+		return err
+	}); err != nil {
+		respondRuleError(c, fc.Log, err, "Failed to waive fines")
+		return
+	}
+
+	logging.Event(c, fc.Log, logging.EventFineWaived, "Fines waived",
+		logging.IDs("fine_ids", waiveRequest.IDs), slog.Int("count", len(fines)))
+	c.JSON(http.StatusOK, gin.H{"message": "Fines waived successfully"})
+}
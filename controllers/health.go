@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"library/apierror"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// How long the readiness probe waits for the database
+const readyTimeout = 2 * time.Second
+
+// Define a struct to hold the database instance
+type HealthController struct {
+	DB       *gorm.DB
+	Log      *slog.Logger
+	draining atomic.Bool
+}
+
+// Constructor function to create a new HealthController
+func NewHealthController(db *gorm.DB, logger *slog.Logger) *HealthController {
+	return &HealthController{DB: db, Log: logger}
+}
+
+// Drain makes the readiness probe fail so load balancers stop sending
+// traffic while in-flight requests finish
+func (hc *HealthController) Drain() {
+	hc.draining.Store(true)
+}
+
+// Liveness answers as long as the process can serve requests at all
+func (hc *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness answers ok only when the database responds and the server is not shutting down
+func (hc *HealthController) Readiness(c *gin.Context) {
+	if hc.draining.Load() {
+		apierror.Abort(c, apierror.ServerDraining)
+		return
+	}
+
+	sqlDB, err := hc.DB.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readyTimeout)
+		defer cancel()
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		hc.Log.WarnContext(c, "Readiness check failed", "error", err)
+		apierror.Abort(c, apierror.DatabaseUnavailable)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
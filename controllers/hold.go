@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"library/apierror"
+	"library/logging"
+	"library/models"
+	"library/repositories"
+	"library/services"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Define a struct to hold the store
+type HoldController struct {
+	Store repositories.Store
+	Log   *slog.Logger
+}
+
+// Constructor function to create a new HoldController
+func NewHoldController(store repositories.Store, logger *slog.Logger) *HoldController {
+	return &HoldController{Store: store, Log: logger}
+}
+
+func (hc *HoldController) PlaceHolds(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		hc.Log.WarnContext(c, "Unauthorized access attempt")
+		apierror.Abort(c, apierror.Unauthorized)
+		return
+	}
+	userData, _ := user.(models.UserResponse)
+
+	var bookTypeIDs models.BookIDsPayload
+	if err := c.ShouldBindJSON(&bookTypeIDs); err != nil {
+		hc.Log.WarnContext(c, "Invalid hold request payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if len(bookTypeIDs.BookTypeIDs) == 0 {
+		hc.Log.WarnContext(c, "Empty hold request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No book IDs provided"))
+		return
+	}
+
+	var holds []models.Hold
+	var holdsResponse []models.HoldResponse
+	if err := hc.Store.Transaction(func(store repositories.Store) error {
+		var err error
+		if holds, err = services.PlaceHolds(c, hc.Log, store, userData.ID, bookTypeIDs.BookTypeIDs, time.Now()); err != nil {
+			return err
+		}
+		holdsResponse, err = services.HoldResponses(store, holds)
+		return err
+	}); err != nil {
+		respondRuleError(c, hc.Log, err, "Failed to place holds")
+		return
+	}
+
+	logging.Event(c, hc.Log, logging.EventHoldPlaced, "Holds placed",
+		logging.IDs("hold_ids", holdIDsOf(holds)), logging.IDs("book_type_ids", bookTypeIDs.BookTypeIDs), slog.Int("count", len(holds)))
+	c.JSON(http.StatusOK, gin.H{"message": "Holds placed successfully", "data": holdsResponse})
+}
+
+func (hc *HoldController) GetHoldList(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		hc.Log.WarnContext(c, "Unauthorized access attempt")
+		apierror.Abort(c, apierror.Unauthorized)
+		return
+	}
+	userData, _ := user.(models.UserResponse)
+
+	var holdSearchRequest models.HoldSearchRequest
+	if err := c.ShouldBindJSON(&holdSearchRequest); err != nil {
+		hc.Log.WarnContext(c, "Invalid hold search request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	if err := hc.Store.Transaction(func(store repositories.Store) error {
+		return services.ExpireHolds(c, hc.Log, store, time.Now())
+	}); err != nil {
+		hc.Log.ErrorContext(c, "Error expiring holds", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch holds"))
+		return
+	}
+
+	var statuses []uint
+	switch holdSearchRequest.Status {
+	case 1:
+		statuses = []uint{1, 2}
+	case 2:
+		statuses = []uint{3, 4, 5}
+	}
+
+	holds, total, err := hc.Store.Holds().List(userData.ID, statuses, holdSearchRequest.Pagination)
+	if err != nil {
+		hc.Log.ErrorContext(c, "Failed to fetch holds", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch holds"))
+		return
+	}
+
+	holdsResponse, err := services.HoldResponses(hc.Store, holds)
+	if err != nil {
+		hc.Log.ErrorContext(c, "Failed to fetch queue position", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch queue position"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"holds": holdsResponse, "total": total})
+}
+
+func (hc *HoldController) CancelHolds(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		hc.Log.WarnContext(c, "Unauthorized access attempt")
+		apierror.Abort(c, apierror.Unauthorized)
+		return
+	}
+	userData, _ := user.(models.UserResponse)
+
+	var holdIDs models.HoldRequest
+	if err := c.ShouldBindJSON(&holdIDs); err != nil {
+		hc.Log.WarnContext(c, "Invalid cancel hold request payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if len(holdIDs.IDs) == 0 {
+		hc.Log.WarnContext(c, "Empty cancel hold request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No hold IDs provided"))
+		return
+	}
+
+	var holds []models.Hold
+	if err := hc.Store.Transaction(func(store repositories.Store) error {
+		var err error
+		holds, err = services.CancelHolds(c, hc.Log, store, userData.ID, holdIDs.IDs, time.Now())
+		return err
+	}); err != nil {
+		respondRuleError(c, hc.Log, err, "Failed to cancel holds")
+		return
+	}
+
+	logging.Event(c, hc.Log, logging.EventHoldCancelled, "Holds cancelled",
+		logging.IDs("hold_ids", holdIDsOf(holds)), slog.Int("count", len(holds)))
+	c.JSON(http.StatusOK, gin.H{"message": "Holds cancelled successfully"})
+}
+
+func holdIDsOf(holds []models.Hold) []uint {
+	ids := make([]uint, len(holds))
+	for i, hold := range holds {
+		ids[i] = hold.ID
+	}
+	return ids
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"library/apierror"
+	"library/models"
+	"library/repositories"
+	"library/services"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Define a struct to hold the store
+type PolicyController struct {
+	Store repositories.Store
+	Log   *slog.Logger
+}
+
+// Constructor function to create a new PolicyController
+func NewPolicyController(store repositories.Store, logger *slog.Logger) *PolicyController {
+	return &PolicyController{Store: store, Log: logger}
+}
+
+func (pc *PolicyController) GetPolicyList(c *gin.Context) {
+	policies, err := pc.Store.Policies().List()
+	if err != nil {
+		pc.Log.ErrorContext(c, "Failed to fetch loan policies", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch loan policies"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": policies, "default": models.DefaultLoanPolicy})
+}
+
+func (pc *PolicyController) SavePolicy(c *gin.Context) {
+	var policyPayload models.LoanPolicyPayload
+	if err := c.ShouldBindJSON(&policyPayload); err != nil {
+		pc.Log.WarnContext(c, "Invalid loan policy payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	policy := models.LoanPolicy{
+		ID:             policyPayload.ID,
+		PatronCategory: policyPayload.PatronCategory,
+		ItemType:       policyPayload.ItemType,
+		LoanDays:       policyPayload.LoanDays,
+		RenewalDays:    policyPayload.RenewalDays,
+		MaxRenewals:    policyPayload.MaxRenewals,
+		MaxLoans:       policyPayload.MaxLoans,
+		MaxHolds:       policyPayload.MaxHolds,
+	}
+
+	if err := pc.Store.Transaction(func(store repositories.Store) error {
+		return services.SavePolicy(store, &policy)
+	}); err != nil {
+		respondRuleError(c, pc.Log, err, "Failed to save loan policy")
+		return
+	}
+
+	pc.Log.InfoContext(c, "Loan policy saved", "policy_id", policy.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Loan policy saved successfully", "data": policy})
+}
+
+func (pc *PolicyController) DeletePolicies(c *gin.Context) {
+	var policyIDs models.LoanPolicyRequest
+	if err := c.ShouldBindJSON(&policyIDs); err != nil {
+		pc.Log.WarnContext(c, "Invalid delete loan policy payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if len(policyIDs.IDs) == 0 {
+		pc.Log.WarnContext(c, "Empty delete loan policy request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No policy IDs provided"))
+		return
+	}
+
+	if err := pc.Store.Policies().Delete(policyIDs.IDs); err != nil {
+		pc.Log.ErrorContext(c, "Failed to delete loan policies", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to delete loan policies"))
+		return
+	}
+
+	pc.Log.InfoContext(c, "Deleted loan policies", "policy_ids", policyIDs.IDs)
+	c.JSON(http.StatusOK, gin.H{"message": "Loan policies deleted successfully"})
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"errors"
+	"library/apierror"
+	"library/logging"
+	"library/models"
+	"library/repositories"
+	"library/services"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (uc *UserController) GetProfile(c *gin.Context) {
+	userFound, ok := uc.signedInUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": userFound.ToProfileResponse()})
+}
+
+func (uc *UserController) UpdateProfile(c *gin.Context) {
+	var profileUpdatePayload models.ProfileUpdatePayload
+	if err := c.ShouldBindJSON(&profileUpdatePayload); err != nil {
+		uc.Log.WarnContext(c, "Invalid profile update request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	userFound, ok := uc.signedInUser(c)
+	if !ok {
+		return
+	}
+
+	nickname, email, phone := userFound.Nickname, userFound.Email, userFound.Phone
+	if profileUpdatePayload.Nickname != nil {
+		nickname = strings.TrimSpace(*profileUpdatePayload.Nickname)
+		if nickname == "" {
+			uc.Log.WarnContext(c, "Blank nickname in profile update")
+			apierror.Abort(c, apierror.InvalidPayload.WithMessage("Nickname cannot be blank"))
+			return
+		}
+	}
+	if profileUpdatePayload.Email != nil {
+		email = models.NormalizeEmail(*profileUpdatePayload.Email)
+		if email != "" && !models.ValidEmail(email) {
+			uc.Log.WarnContext(c, "Invalid email in profile update")
+			apierror.Abort(c, apierror.InvalidEmail)
+			return
+		}
+	}
+	if profileUpdatePayload.Phone != nil {
+		phone = models.NormalizePhone(*profileUpdatePayload.Phone)
+		if phone != "" && !models.ValidPhone(phone) {
+			uc.Log.WarnContext(c, "Invalid phone in profile update")
+			apierror.Abort(c, apierror.InvalidPhone)
+			return
+		}
+	}
+
+	var fields []string
+	if nickname != userFound.Nickname {
+		fields = append(fields, "nickname")
+	}
+	if email != userFound.Email {
+		fields = append(fields, "email")
+	}
+	if phone != userFound.Phone {
+		fields = append(fields, "phone")
+	}
+
+	// Nothing changed, nothing to audit
+	if len(fields) > 0 {
+		if err := uc.Store.Transaction(func(store repositories.Store) error {
+			if err := store.Users().UpdateProfile(userFound.ID, nickname, email, phone); err != nil {
+				return err
+			}
+			return store.Audit().Record(auditEntry(c, userFound.ID, logging.EventProfileUpdated, fields...))
+		}); err != nil {
+			uc.Log.ErrorContext(c, "Failed to update profile", "error", err)
+			apierror.Abort(c, apierror.Internal("Failed to update profile"))
+			return
+		}
+		logging.Event(c, uc.Log, logging.EventProfileUpdated, "Profile updated", slog.Any("fields", fields))
+	}
+
+	userFound.Nickname, userFound.Email, userFound.Phone = nickname, email, phone
+	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully", "user": userFound.ToProfileResponse()})
+}
+
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	var passwordChangePayload models.PasswordChangePayload
+	if err := c.ShouldBindJSON(&passwordChangePayload); err != nil {
+		uc.Log.WarnContext(c, "Invalid password change request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	userFound, ok := uc.signedInUser(c)
+	if !ok {
+		return
+	}
+
+	// Check the current password as SignIn does
+	if err := bcrypt.CompareHashAndPassword([]byte(userFound.Password), []byte(passwordChangePayload.OldPassword)); err != nil {
+		uc.Log.WarnContext(c, "Password change with wrong current password")
+		apierror.Abort(c, apierror.WrongPassword)
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(passwordChangePayload.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		uc.Log.ErrorContext(c, "Failed to hash password", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to hash password"))
+		return
+	}
+
+	// Other sessions may have been opened with the old password, this one stays
+	now := time.Now()
+	sessionID := c.GetUint("session_id")
+	if err := uc.Store.Transaction(func(store repositories.Store) error {
+		if err := store.Users().UpdatePassword(userFound.ID, string(passwordHash)); err != nil {
+			return err
+		}
+		sessions, err := store.Sessions().ListActive(userFound.ID, now)
+		if err != nil {
+			return err
+		}
+		var others []uint
+		for _, session := range sessions {
+			if session.ID != sessionID {
+				others = append(others, session.ID)
+			}
+		}
+		if len(others) > 0 {
+			if err := store.Sessions().Revoke(userFound.ID, others, now); err != nil {
+				return err
+			}
+		}
+		return store.Audit().Record(auditEntry(c, userFound.ID, logging.EventPasswordChange, "password"))
+	}); err != nil {
+		uc.Log.ErrorContext(c, "Failed to change password", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to change password"))
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventPasswordChange, "Password changed")
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+func (uc *UserController) DeleteAccount(c *gin.Context) {
+	var accountDeletePayload models.AccountDeletePayload
+	if err := c.ShouldBindJSON(&accountDeletePayload); err != nil {
+		uc.Log.WarnContext(c, "Invalid account delete request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	userFound, ok := uc.signedInUser(c)
+	if !ok {
+		return
+	}
+
+	// The last admin deleting themselves would lock everyone out
+	if userFound.Role == models.RoleAdmin {
+		uc.Log.WarnContext(c, "Admin attempted to delete own account")
+		apierror.Abort(c, apierror.SelfActionForbidden.WithMessage("Admins cannot delete their own account"))
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(userFound.Password), []byte(accountDeletePayload.Password)); err != nil {
+		uc.Log.WarnContext(c, "Account deletion with wrong password")
+		apierror.Abort(c, apierror.WrongPassword)
+		return
+	}
+
+	// Refused while books are still on loan
+	if err := uc.Store.Transaction(func(store repositories.Store) error {
+		if err := services.DeleteUser(c, uc.Log, store, userFound.ID, time.Now()); err != nil {
+			return err
+		}
+		return store.Audit().Record(auditEntry(c, userFound.ID, logging.EventAccountDeleted))
+	}); err != nil {
+		if errors.Is(err, services.ErrOpenLoans) {
+			uc.Log.WarnContext(c, "Attempted to delete account with open records")
+			apierror.Abort(c, apierror.UsersHaveLoans.WithMessage("Return your books before deleting your account"))
+			return
+		}
+		uc.Log.ErrorContext(c, "Failed to delete account", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to delete account"))
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventAccountDeleted, "Account deleted", slog.String("username", userFound.Username))
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+// signedInUser loads the account of the signed in user, aborting when it is gone
+func (uc *UserController) signedInUser(c *gin.Context) (models.User, bool) {
+	user, _ := c.Get("user")
+	userData, _ := user.(models.UserResponse)
+
+	userFound, err := uc.Users.FindByID(userData.ID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		uc.Log.WarnContext(c, "Signed in user not found")
+		apierror.Abort(c, apierror.UserNotFound)
+		return models.User{}, false
+	}
+	if err != nil {
+		uc.Log.ErrorContext(c, "Failed to fetch user", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch user"))
+		return models.User{}, false
+	}
+	return userFound, true
+}
+
+// auditEntry records a change users made to their own account
+func auditEntry(c *gin.Context, userID uint, action string, fields ...string) *models.AuditEntry {
+	return &models.AuditEntry{
+		UserID:    userID,
+		ActorID:   userID,
+		Action:    action,
+		Fields:    strings.Join(fields, ","),
+		RequestID: c.GetString(logging.RequestIDKey),
+	}
+}
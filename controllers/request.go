@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"errors"
+	"library/apierror"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidID = errors.New("id must be a positive integer")
+
+// bindSearch reads list filters from the query string on the GET routes of
+// /api/v1 and from the JSON body on the older POST routes
+func bindSearch(c *gin.Context, request any) error {
+	if c.Request.Method == http.MethodGet {
+		return c.ShouldBindQuery(request)
+	}
+	return c.ShouldBindJSON(request)
+}
+
+// bindIDs reads the IDs a request acts on: the one in the path on /api/v1
+// routes such as /loans/:id, the "ids" list of the JSON body otherwise
+func bindIDs(c *gin.Context, body any, ids *[]uint) error {
+	if _, ok := c.Params.Get("id"); !ok {
+		return c.ShouldBindJSON(body)
+	}
+	id, err := pathID(c)
+	if err != nil {
+		return err
+	}
+	*ids = []uint{id}
+	return nil
+}
+
+// pathID parses the :id path parameter
+func pathID(c *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		return 0, errInvalidID
+	}
+	return uint(id), nil
+}
+
+// invalidRequest is the API error for a request bindSearch or bindIDs rejected
+func invalidRequest(err error) *apierror.Error {
+	if errors.Is(err, errInvalidID) {
+		return apierror.InvalidID
+	}
+	return apierror.InvalidPayload
+}
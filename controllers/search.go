@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"library/apierror"
+	"library/models"
+	"library/search"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Define a struct to hold the catalog searcher
+type SearchController struct {
+	Searcher *search.Searcher
+	Log      *slog.Logger
+}
+
+// Constructor function to create a new SearchController
+func NewSearchController(searcher *search.Searcher, logger *slog.Logger) *SearchController {
+	return &SearchController{Searcher: searcher, Log: logger}
+}
+
+func (sc *SearchController) SearchBooks(c *gin.Context) {
+	var searchRequest models.SearchRequest
+	if err := bindSearch(c, &searchRequest); err != nil {
+		sc.Log.WarnContext(c, "Invalid search request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	response, err := sc.Searcher.Search(c, searchRequest)
+	if err != nil {
+		sc.Log.ErrorContext(c, "Catalog search failed", "query", searchRequest.Query, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to search the catalog"))
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
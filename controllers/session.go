@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"library/apierror"
+	"library/logging"
+	"library/models"
+	"library/repositories"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errRefreshTokenReused = errors.New("refresh token already used")
+
+func (uc *UserController) RefreshToken(c *gin.Context) {
+	var refreshPayload models.RefreshPayload
+	if err := c.ShouldBindJSON(&refreshPayload); err != nil {
+		uc.Log.WarnContext(c, "Invalid refresh request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	refreshToken, err := uc.Sessions.FindRefreshToken(hashToken(refreshPayload.RefreshToken))
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			uc.Log.WarnContext(c, "Unknown refresh token presented")
+			apierror.Abort(c, apierror.RefreshTokenInvalid)
+			return
+		}
+		uc.Log.ErrorContext(c, "Failed to fetch refresh token", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to refresh token"))
+		return
+	}
+	session := refreshToken.Session
+	c.Set(logging.UserIDKey, session.UserID)
+
+	now := time.Now()
+	if !session.Active(now) {
+		uc.Log.WarnContext(c, "Refresh attempted on inactive session", "session_id", session.ID)
+		apierror.Abort(c, apierror.SessionRevoked)
+		return
+	}
+
+	var tokens gin.H
+	err = uc.Store.Transaction(func(store repositories.Store) error {
+		// Each refresh token works once, a second use means it was copied
+		unused, err := store.Sessions().UseRefreshToken(refreshToken.ID, now)
+		if err != nil {
+			return err
+		}
+		if !unused {
+			return errRefreshTokenReused
+		}
+
+		user, err := store.Users().FindByID(session.UserID)
+		if err != nil {
+			return err
+		}
+		if err := store.Sessions().Touch(session.ID, now); err != nil {
+			return err
+		}
+		tokens, err = uc.issueTokens(store, session.ID, user.ID, user.Role)
+		return err
+	})
+	switch {
+	case errors.Is(err, errRefreshTokenReused):
+		logging.Event(c, uc.Log, logging.EventTokenReuse, "Refresh token reuse detected, revoking all sessions",
+			slog.Uint64("session_id", uint64(session.ID)))
+		if err := uc.Sessions.Revoke(session.UserID, nil, now); err != nil {
+			uc.Log.ErrorContext(c, "Failed to revoke sessions", "error", err)
+		}
+		apierror.Abort(c, apierror.RefreshTokenReused)
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		uc.Log.WarnContext(c, "Failed to fetch session user", "session_id", session.ID, "error", err)
+		apierror.Abort(c, apierror.RefreshTokenInvalid)
+		return
+	case err != nil:
+		uc.Log.ErrorContext(c, "Failed to refresh session", "session_id", session.ID, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to refresh token"))
+		return
+	}
+
+	uc.Log.InfoContext(c, "Session refreshed", "session_id", session.ID)
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (uc *UserController) Logout(c *gin.Context) {
+	user, _ := c.Get("user")
+	userData, _ := user.(models.UserResponse)
+	sessionID := c.GetUint("session_id")
+
+	if err := uc.Sessions.Revoke(userData.ID, []uint{sessionID}, time.Now()); err != nil {
+		uc.Log.ErrorContext(c, "Failed to revoke session", "session_id", sessionID, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to log out"))
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventLogout, "Logged out", slog.Uint64("session_id", uint64(sessionID)))
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func (uc *UserController) LogoutAll(c *gin.Context) {
+	user, _ := c.Get("user")
+	userData, _ := user.(models.UserResponse)
+
+	if err := uc.Sessions.Revoke(userData.ID, nil, time.Now()); err != nil {
+		uc.Log.ErrorContext(c, "Failed to revoke sessions", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to log out"))
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventLogout, "Logged out of all sessions", slog.Bool("all", true))
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions successfully"})
+}
+
+func (uc *UserController) GetSessionList(c *gin.Context) {
+	user, _ := c.Get("user")
+	userData, _ := user.(models.UserResponse)
+
+	sessions, err := uc.Sessions.ListActive(userData.ID, time.Now())
+	if err != nil {
+		uc.Log.ErrorContext(c, "Failed to fetch sessions", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch sessions"))
+		return
+	}
+
+	currentID := c.GetUint("session_id")
+	sessionsResponse := make([]models.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		sessionsResponse[i] = session.ToResponse(currentID)
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessionsResponse})
+}
+
+func (uc *UserController) RevokeSessions(c *gin.Context) {
+	user, _ := c.Get("user")
+	userData, _ := user.(models.UserResponse)
+
+	var sessionRequest models.SessionRequest
+	if err := c.ShouldBindJSON(&sessionRequest); err != nil {
+		uc.Log.WarnContext(c, "Invalid revoke session payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if len(sessionRequest.IDs) == 0 {
+		uc.Log.WarnContext(c, "Empty revoke session request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No session IDs provided"))
+		return
+	}
+
+	if err := uc.Sessions.Revoke(userData.ID, sessionRequest.IDs, time.Now()); err != nil {
+		uc.Log.ErrorContext(c, "Failed to revoke sessions", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to revoke sessions"))
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventSessionRevoked, "Sessions revoked", logging.IDs("session_ids", sessionRequest.IDs))
+	c.JSON(http.StatusOK, gin.H{"message": "Sessions revoked successfully"})
+}
+
+func (uc *UserController) RevokeUserSessions(c *gin.Context) {
+	var userSessionRequest models.UserSessionRequest
+	if err := c.ShouldBindJSON(&userSessionRequest); err != nil {
+		uc.Log.WarnContext(c, "Invalid revoke user sessions payload", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	if err := uc.Sessions.Revoke(userSessionRequest.UserID, nil, time.Now()); err != nil {
+		uc.Log.ErrorContext(c, "Failed to revoke sessions", "target_user_id", userSessionRequest.UserID, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to revoke sessions"))
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventSessionRevoked, "All sessions of user revoked",
+		slog.Uint64("target_user_id", uint64(userSessionRequest.UserID)), slog.Bool("all", true))
+	c.JSON(http.StatusOK, gin.H{"message": "Sessions revoked successfully"})
+}
+
+// startSession opens a new session for the user and issues its first token pair
+func (uc *UserController) startSession(store repositories.Store, user models.User, userAgent string) (gin.H, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		ExpiresAt:  now.Add(uc.Auth.SessionTTL()),
+		LastUsedAt: now,
+	}
+	if err := store.Sessions().Create(&session); err != nil {
+		return nil, err
+	}
+	return uc.issueTokens(store, session.ID, user.ID, user.Role)
+}
+
+// issueTokens pairs a short lived access token with the next refresh token of the session
+func (uc *UserController) issueTokens(store repositories.Store, sessionID, userID uint, role string) (gin.H, error) {
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Sessions().CreateRefreshToken(&models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: hashToken(refreshToken),
+	}); err != nil {
+		return nil, err
+	}
+
+	token, err := uc.generateJWT(userID, role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(uc.Auth.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+func newRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
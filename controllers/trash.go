@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"errors"
+	"library/apierror"
+	"library/logging"
+	"library/models"
+	"library/repositories"
+	"library/services"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Define a struct to hold the store
+type TrashController struct {
+	Store repositories.Store
+	Log   *slog.Logger
+}
+
+// Constructor function to create a new TrashController
+func NewTrashController(store repositories.Store, logger *slog.Logger) *TrashController {
+	return &TrashController{Store: store, Log: logger}
+}
+
+func (tc *TrashController) GetTrashList(c *gin.Context) {
+	var trashRequest models.TrashRequest
+	if err := c.ShouldBindJSON(&trashRequest); err != nil {
+		tc.Log.WarnContext(c, "Invalid trash request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	items, total, err := tc.Store.Trash().List(trashRequest.Type, trashRequest.Pagination)
+	if err != nil {
+		tc.Log.ErrorContext(c, "Failed to fetch deleted rows", "type", trashRequest.Type, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to fetch deleted rows"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total})
+}
+
+func (tc *TrashController) RestoreTrash(c *gin.Context) {
+	var restoreRequest models.TrashRestoreRequest
+	if err := c.ShouldBindJSON(&restoreRequest); err != nil {
+		tc.Log.WarnContext(c, "Invalid restore request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if len(restoreRequest.IDs) == 0 {
+		tc.Log.WarnContext(c, "Empty restore request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No IDs provided"))
+		return
+	}
+
+	var failedID uint
+	now := time.Now()
+	if err := tc.Store.Transaction(func(store repositories.Store) error {
+		for _, id := range restoreRequest.IDs {
+			if err := services.Restore(c, tc.Log, store, restoreRequest.Type, id, now); err != nil {
+				failedID = id
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotInTrash):
+			tc.Log.WarnContext(c, "Restore requested for row which is not deleted", "type", restoreRequest.Type, "id", failedID)
+			apierror.Abort(c, apierror.NotInTrash.With("id", failedID))
+		case errors.Is(err, services.ErrParentDeleted):
+			tc.Log.WarnContext(c, "Restore requested for row whose parent is deleted", "type", restoreRequest.Type, "id", failedID)
+			apierror.Abort(c, apierror.ParentDeleted.With("id", failedID))
+		default:
+			tc.Log.ErrorContext(c, "Failed to restore deleted row", "type", restoreRequest.Type, "id", failedID, "error", err)
+			apierror.Abort(c, apierror.Internal("Failed to restore deleted rows"))
+		}
+		return
+	}
+
+	logging.Event(c, tc.Log, logging.EventRestored, "Restored deleted rows",
+		slog.String("type", restoreRequest.Type), logging.IDs("ids", restoreRequest.IDs))
+	c.JSON(http.StatusOK, gin.H{"message": "Restored successfully"})
+}
@@ -1,26 +1,34 @@
 package controllers
 
 import (
+	"errors"
+	"library/apierror"
+	"library/config"
+	"library/logging"
 	"library/models"
-	"log"
+	"library/repositories"
+	"library/services"
+	"log/slog"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
-// Define a struct to hold the database instance
+// Define a struct to hold the repositories
 type UserController struct {
-	DB *gorm.DB
+	Auth     config.AuthConfig
+	Store    repositories.Store
+	Users    repositories.UserRepository
+	Sessions repositories.SessionRepository
+	Log      *slog.Logger
 }
 
-// Constructor function to create a new BookController
-func NewUserController(db *gorm.DB) *UserController {
-	return &UserController{DB: db}
+// Constructor function to create a new UserController
+func NewUserController(store repositories.Store, auth config.AuthConfig, logger *slog.Logger) *UserController {
+	return &UserController{Auth: auth, Store: store, Users: store.Users(), Sessions: store.Sessions(), Log: logger}
 }
 
 func (uc *UserController) CreateUser(c *gin.Context) {
@@ -28,29 +36,29 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 
 	// Validate request payload
 	if err := c.ShouldBindJSON(&signUpPayload); err != nil {
-		log.Printf("Invalid signup request: %v\n", err)
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request payload"})
+		uc.Log.WarnContext(c, "Invalid signup request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
 		return
 	}
 
-	// Check if the username already exists
-	count := int64(0)
-	if err := uc.DB.Model(&models.User{}).Where("username = ?", signUpPayload.Username).Count(&count).Error; err != nil {
-		log.Printf("Error counting existing user: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check username exists"})
+	// Check if the username already exists, deleted users keep theirs until purged
+	taken, err := uc.Users.UsernameTaken(signUpPayload.Username)
+	if err != nil {
+		uc.Log.ErrorContext(c, "Error counting existing user", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to check username exists"))
 		return
 	}
-	if count > 0 {
-		log.Printf("Username already in use: %s\n", signUpPayload.Username)
-		c.JSON(http.StatusConflict, gin.H{"error": "Username already in use"})
+	if taken {
+		uc.Log.WarnContext(c, "Username already in use", "username", signUpPayload.Username)
+		apierror.Abort(c, apierror.UsernameTaken)
 		return
 	}
 
 	// Hash password
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(signUpPayload.Password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("Failed to hash password: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		uc.Log.ErrorContext(c, "Failed to hash password", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to hash password"))
 		return
 	}
 
@@ -58,20 +66,18 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 		Nickname: signUpPayload.Nickname,
 		Username: signUpPayload.Username,
 		Password: string(passwordHash),
+		Role:     models.RolePatron,
 	}
 
-	// Use a transaction for safety
-	tx := uc.DB.Begin()
-	if err := tx.Create(&user).Error; err != nil {
-		tx.Rollback()
-		log.Printf("Failed to create user: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	if err := uc.Users.Create(&user); err != nil {
+		uc.Log.ErrorContext(c, "Failed to create user", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to create user"))
 		return
 	}
-	tx.Commit()
 
-	log.Printf("User %s created successfully\n", user.Username)
-	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "data": user})
+	c.Set(logging.UserIDKey, user.ID)
+	logging.Event(c, uc.Log, logging.EventSignup, "User created", slog.String("username", user.Username))
+	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "data": user.ToProfileResponse()})
 }
 
 func (uc *UserController) SignIn(c *gin.Context) {
@@ -79,36 +85,40 @@ func (uc *UserController) SignIn(c *gin.Context) {
 
 	// Validate request payload
 	if err := c.ShouldBindJSON(&signInPayload); err != nil {
-		log.Printf("Invalid signin request: %v\n", err)
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid request payload"})
+		uc.Log.WarnContext(c, "Invalid signin request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
 		return
 	}
 
 	// Find user by username
-	var userFound models.User
-	if err := uc.DB.Where("username = ?", signInPayload.Username).First(&userFound).Error; err != nil {
-		log.Printf("User not found: %s\n", signInPayload.Username)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	userFound, err := uc.Users.FindByUsername(signInPayload.Username)
+	if err != nil {
+		uc.Log.WarnContext(c, "Signin for unknown user", "username", signInPayload.Username)
+		apierror.Abort(c, apierror.InvalidCredentials)
 		return
 	}
 
 	// Compare hashed password
 	if err := bcrypt.CompareHashAndPassword([]byte(userFound.Password), []byte(signInPayload.Password)); err != nil {
-		log.Printf("Invalid password for user: %s\n", signInPayload.Username)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		uc.Log.WarnContext(c, "Signin with invalid password", "username", signInPayload.Username)
+		apierror.Abort(c, apierror.InvalidCredentials)
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateJWT(userFound.ID)
-	if err != nil {
-		log.Printf("Failed to generate token for user: %s\n", signInPayload.Username)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	// Open a session and generate its tokens
+	var tokens gin.H
+	if err := uc.Store.Transaction(func(store repositories.Store) error {
+		tokens, err = uc.startSession(store, userFound, c.Request.UserAgent())
+		return err
+	}); err != nil {
+		uc.Log.ErrorContext(c, "Failed to generate token", "username", signInPayload.Username, "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to generate token"))
 		return
 	}
 
-	log.Printf("User %s signed in successfully\n", userFound.Username)
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.Set(logging.UserIDKey, userFound.ID)
+	logging.Event(c, uc.Log, logging.EventSignin, "User signed in", slog.String("username", userFound.Username))
+	c.JSON(http.StatusOK, tokens)
 }
 
 func (uc *UserController) GetUserInfo(c *gin.Context) {
@@ -118,18 +128,121 @@ func (uc *UserController) GetUserInfo(c *gin.Context) {
 	})
 }
 
-// generateJWT creates a JWT token
-func generateJWT(userID uint) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":  userID,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-	})
+func (uc *UserController) UpdateUserRole(c *gin.Context) {
+	user, _ := c.Get("user")
+	adminData, _ := user.(models.UserResponse)
 
-	secret := os.Getenv("SECRET")
-	if secret == "" {
-		log.Println("JWT secret is not set")
-		return "", http.ErrServerClosed
+	var userRoleRequest models.UserRoleRequest
+	if err := c.ShouldBindJSON(&userRoleRequest); err != nil {
+		uc.Log.WarnContext(c, "Invalid user role request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	// Admins cannot demote themselves and lock everyone out
+	if userRoleRequest.UserID == adminData.ID && userRoleRequest.Role != models.RoleAdmin {
+		uc.Log.WarnContext(c, "Admin attempted to change own role")
+		apierror.Abort(c, apierror.SelfActionForbidden.WithMessage("You are not allowed to change your own role"))
+		return
 	}
 
-	return token.SignedString([]byte(secret))
+	found, err := uc.Users.UpdateRole(userRoleRequest.UserID, userRoleRequest.Role)
+	if err != nil {
+		uc.Log.ErrorContext(c, "Failed to update user role", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to update user role"))
+		return
+	}
+	if !found {
+		uc.Log.WarnContext(c, "User not found", "target_user_id", userRoleRequest.UserID)
+		apierror.Abort(c, apierror.UserNotFound)
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventRoleChanged, "User role changed",
+		slog.Uint64("target_user_id", uint64(userRoleRequest.UserID)), slog.String("role", userRoleRequest.Role))
+	c.JSON(http.StatusOK, gin.H{"message": "User role updated successfully"})
+}
+
+func (uc *UserController) UpdateUserCategory(c *gin.Context) {
+	var userCategoryRequest models.UserCategoryRequest
+	if err := c.ShouldBindJSON(&userCategoryRequest); err != nil {
+		uc.Log.WarnContext(c, "Invalid user category request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+
+	found, err := uc.Users.UpdateCategory(userCategoryRequest.UserID, userCategoryRequest.Category)
+	if err != nil {
+		uc.Log.ErrorContext(c, "Failed to update user category", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to update user category"))
+		return
+	}
+	if !found {
+		uc.Log.WarnContext(c, "User not found", "target_user_id", userCategoryRequest.UserID)
+		apierror.Abort(c, apierror.UserNotFound)
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventCategorySet, "User category changed",
+		slog.Uint64("target_user_id", uint64(userCategoryRequest.UserID)), slog.String("category", userCategoryRequest.Category))
+	c.JSON(http.StatusOK, gin.H{"message": "User category updated successfully"})
+}
+
+func (uc *UserController) DeleteUsers(c *gin.Context) {
+	user, _ := c.Get("user")
+	adminData, _ := user.(models.UserResponse)
+
+	var userDeleteRequest models.UserDeleteRequest
+	if err := c.ShouldBindJSON(&userDeleteRequest); err != nil {
+		uc.Log.WarnContext(c, "Invalid delete user request", "error", err)
+		apierror.Abort(c, apierror.InvalidPayload)
+		return
+	}
+	if len(userDeleteRequest.IDs) == 0 {
+		uc.Log.WarnContext(c, "Empty delete user request")
+		apierror.Abort(c, apierror.EmptyRequest.WithMessage("No user IDs provided"))
+		return
+	}
+	for _, id := range userDeleteRequest.IDs {
+		if id == adminData.ID {
+			uc.Log.WarnContext(c, "Admin attempted to delete own account")
+			apierror.Abort(c, apierror.SelfActionForbidden.WithMessage("You are not allowed to delete your own account here"))
+			return
+		}
+	}
+
+	// Users with books still on loan stay until they return them
+	now := time.Now()
+	if err := uc.Store.Transaction(func(store repositories.Store) error {
+		for _, id := range userDeleteRequest.IDs {
+			if err := services.DeleteUser(c, uc.Log, store, id, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, services.ErrOpenLoans) {
+			uc.Log.WarnContext(c, "Attempted to delete users with open records", "target_user_ids", userDeleteRequest.IDs)
+			apierror.Abort(c, apierror.UsersHaveLoans)
+			return
+		}
+		uc.Log.ErrorContext(c, "Failed to delete users", "error", err)
+		apierror.Abort(c, apierror.Internal("Failed to delete users"))
+		return
+	}
+
+	logging.Event(c, uc.Log, logging.EventUsersDeleted, "Users deleted", logging.IDs("target_user_ids", userDeleteRequest.IDs))
+	c.JSON(http.StatusOK, gin.H{"message": "Users deleted successfully"})
+}
+
+// generateJWT creates a short lived access token bound to a session
+func (uc *UserController) generateJWT(userID uint, role string, sessionID uint) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":   userID,
+		"role": role,
+		"sid":  sessionID,
+		"exp":  time.Now().Add(uc.Auth.AccessTokenTTL()).Unix(),
+	})
+
+	return token.SignedString([]byte(uc.Auth.Secret))
 }
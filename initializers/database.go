@@ -1,21 +1,53 @@
 package initializers
 
 import (
+	"fmt"
+	"library/config"
 	"log"
-	"os"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func ConnectDB() {
-	dsn := os.Getenv("DB_URL")
+func ConnectDB(cfg config.DatabaseConfig) {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = OpenDB(cfg.Driver, cfg.URL)
 
 	if err != nil {
 		log.Fatal("Failed to connect to DB:", err)
 	}
-}
\ No newline at end of file
+}
+
+// OpenDB opens a postgres (the default) or sqlite database
+func OpenDB(driver, dsn string) (*gorm.DB, error) {
+	config := &gorm.Config{TranslateError: true}
+	switch driver {
+	case "", "postgres":
+		return gorm.Open(postgres.Open(dsn), config)
+	case "sqlite":
+		db, err := gorm.Open(sqlite.Open(dsn), config)
+		if err != nil {
+			return nil, err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		// SQLite takes one writer at a time, and an in-memory database only
+		// lives as long as its connection, so everything shares one
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+			return nil, err
+		}
+		if err := db.Exec("PRAGMA busy_timeout = 5000").Error; err != nil {
+			return nil, err
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q", driver)
+	}
+}
@@ -0,0 +1,63 @@
+// Package logging sets up the structured logger and the request middleware
+// that tags every line logged while serving a request with its request ID
+// and, once authenticated, its user ID.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Keys the middleware stores request values under in the gin context
+const (
+	RequestIDKey = "request_id"
+	UserIDKey    = "user_id"
+)
+
+// New returns a JSON logger writing records at level and above to w
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(&contextHandler{Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// Discard returns a logger that drops everything
+func Discard() *slog.Logger {
+	return New(io.Discard, slog.LevelError+1)
+}
+
+// ParseLevel reads debug, info, warn or error
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(value))); err != nil {
+		return level, fmt.Errorf("unknown log level %q", value)
+	}
+	return level, nil
+}
+
+// contextHandler adds the request and user IDs found in the context passed
+// to the *Context logging methods. A gin.Context works as that context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if requestID, ok := ctx.Value(RequestIDKey).(string); ok {
+			record.AddAttrs(slog.String(RequestIDKey, requestID))
+		}
+		if userID, ok := ctx.Value(UserIDKey).(uint); ok {
+			record.AddAttrs(slog.Uint64(UserIDKey, uint64(userID)))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -1,52 +1,146 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"library/config"
 	"library/controllers"
 	"library/initializers"
+	"library/logging"
+	"library/metrics"
 	"library/middlewares"
+	"library/repositories"
+	"library/routes"
+	"library/workers"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 
 	"github.com/gin-gonic/gin"
 )
 
-func init() {
-	initializers.GetEnvs()
-	initializers.ConnectDB()
-}
-
 func main() {
-	router := gin.Default()
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML config file")
+	printConfig := flag.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if *printConfig {
+		printEffectiveConfig(cfg, err)
+		return
+	}
+	if err != nil {
+		log.Fatal("Invalid config: ", err)
+	}
+
+	// JSON lines on stdout; the standard log package writes through it too
+	level, _ := cfg.Log.SlogLevel()
+	logger := logging.New(os.Stdout, level)
+	slog.SetDefault(logger)
+
+	initializers.ConnectDB(cfg.Database)
+
+	// Cancelled on SIGINT or SIGTERM, which starts the shutdown below
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store := repositories.NewStore(initializers.DB)
+	appMetrics := metrics.New(initializers.DB, store.Records(), logger)
+
+	router := gin.New()
+	router.Use(logging.RequestID(), logging.AccessLog(logger), logging.Recovery(logger), appMetrics.Middleware(), middlewares.ErrorHandler(logger))
+	router.NoRoute(middlewares.NoRoute)
 
 	// Allow CORS
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000"}, // Change to frontend URL
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowOrigins:     cfg.CORS.AllowOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", logging.RequestIDHeader},
+		ExposeHeaders:    []string{logging.RequestIDHeader},
 		AllowCredentials: true,
 	}))
 
-	userController := controllers.NewUserController(initializers.DB)
-	userRouter := router.Group("/user")
-	{
-		userRouter.POST("/signup", userController.CreateUser)
-		userRouter.POST("/signin", userController.SignIn)
-		userRouter.GET("/info", middlewares.CheckAuth, userController.GetUserInfo)
+	checkAuth := middlewares.CheckAuth(initializers.DB, cfg.Auth)
+	healthController := controllers.NewHealthController(initializers.DB, logger)
+	routes.RegisterRoutes(router, routes.Deps{
+		DB:      initializers.DB,
+		Store:   store,
+		Auth:    cfg.Auth,
+		Fines:   cfg.Fines.Policy(),
+		Health:  healthController,
+		Metrics: appMetrics,
+		Log:     logger,
+	}, routes.Auth{Session: checkAuth, Patron: checkAuth, Staff: checkAuth})
+
+	var background sync.WaitGroup
+	purgeWorker := workers.NewPurgeWorker(initializers.DB, cfg.Purge.Retention(), cfg.Purge.Interval(), logger)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		purgeWorker.Run(ctx)
+	}()
+
+	server := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout(),
+		WriteTimeout: cfg.Server.WriteTimeout(),
+		IdleTimeout:  cfg.Server.IdleTimeout(),
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("Listening", "addr", server.Addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Server stopped", "error", err)
+		}
+		stop()
+	case <-ctx.Done():
+		// Fail /readyz but keep serving until load balancers have noticed
+		// and stopped sending new requests
+		logger.Info("Shutting down, draining in-flight requests", "drain_delay", cfg.Server.DrainDelay().String())
+		healthController.Drain()
+		time.Sleep(cfg.Server.DrainDelay())
 	}
 
-	bookController := controllers.NewBookController(initializers.DB)
-	bookRouter := router.Group("/book")
-	{
-		bookRouter.POST("/list", bookController.GetBookList)
-		bookRouter.POST("/borrow", middlewares.CheckAuth, bookController.BorrowBooks)
+	// Stop taking new work, let in-flight requests finish, then stop the
+	// workers and close the pool once nothing is using it
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout())
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Requests still running were cut off", "timeout", cfg.Server.ShutdownTimeout().String(), "error", err)
+	}
+	background.Wait()
+	if sqlDB, err := initializers.DB.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("Failed to close database", "error", err)
+		}
 	}
+	logger.Info("Shutdown complete")
+}
 
-	recordController := controllers.NewRecordController(initializers.DB)
-	recordRouter := router.Group("/record")
-	{
-		recordRouter.POST("/list", middlewares.CheckAuth, recordController.GetRecordList)
-		recordRouter.POST("/extend", middlewares.CheckAuth, recordController.ExtendRecords)
-		recordRouter.POST("/return", middlewares.CheckAuth, recordController.ReturnRecords)
+// printEffectiveConfig shows the config the server would start with, and
+// what is wrong with it when it would not start
+func printEffectiveConfig(cfg config.Config, loadErr error) {
+	out, err := cfg.YAML()
+	if err != nil {
+		log.Fatal("Failed to render config: ", err)
+	}
+	fmt.Print(out)
+	if loadErr != nil {
+		log.Fatal("Invalid config: ", loadErr)
 	}
-	router.Run()
 }
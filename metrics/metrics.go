@@ -0,0 +1,147 @@
+// Package metrics collects the Prometheus metrics served on /metrics: request
+// latency per route, database pool stats and circulation activity.
+package metrics
+
+import (
+	"library/repositories"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+// Where a loan started or ended: self service by the patron or at the desk by staff
+const (
+	ChannelSelf = "self"
+	ChannelDesk = "desk"
+)
+
+// Metrics owns its registry so each server, and each test router, counts on its own
+type Metrics struct {
+	registry        *prometheus.Registry
+	requestDuration *prometheus.HistogramVec
+	borrows         *prometheus.CounterVec
+	returns         *prometheus.CounterVec
+	extensions      prometheus.Counter
+	borrowFailures  *prometheus.CounterVec
+}
+
+// Constructor function to create the metrics of one server
+func New(db *gorm.DB, records repositories.RecordRepository, logger *slog.Logger) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "library_http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		borrows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "library_borrows_total",
+			Help: "Copies lent out.",
+		}, []string{"channel"}),
+		returns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "library_returns_total",
+			Help: "Copies returned.",
+		}, []string{"channel"}),
+		extensions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "library_extensions_total",
+			Help: "Loans renewed.",
+		}),
+		borrowFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "library_borrow_failures_total",
+			Help: "Borrow requests refused, by reason.",
+		}, []string{"reason"}),
+	}
+
+	m.registry.MustRegister(
+		m.requestDuration,
+		m.borrows,
+		m.returns,
+		m.extensions,
+		m.borrowFailures,
+		newLoanCollector(records, logger),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if sqlDB, err := db.DB(); err == nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(sqlDB, "library"))
+	}
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware times every request. Unknown paths share one route label so
+// scanners cannot blow up the number of series.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *Metrics) Borrowed(channel string, count int) {
+	m.borrows.WithLabelValues(channel).Add(float64(count))
+}
+
+func (m *Metrics) Returned(channel string, count int) {
+	m.returns.WithLabelValues(channel).Add(float64(count))
+}
+
+func (m *Metrics) Extended(count int) {
+	m.extensions.Add(float64(count))
+}
+
+// NoCopyAvailable counts a borrow refused because every copy of a title was out
+func (m *Metrics) NoCopyAvailable() {
+	m.borrowFailures.WithLabelValues("no_copy_available").Inc()
+}
+
+// loanCollector reads the loan gauges from the database on each scrape, so
+// they stay right across restarts and replicas
+type loanCollector struct {
+	records repositories.RecordRepository
+	log     *slog.Logger
+	active  *prometheus.Desc
+	overdue *prometheus.Desc
+}
+
+func newLoanCollector(records repositories.RecordRepository, logger *slog.Logger) *loanCollector {
+	return &loanCollector{
+		records: records,
+		log:     logger,
+		active:  prometheus.NewDesc("library_active_loans", "Copies currently lent out.", nil, nil),
+		overdue: prometheus.NewDesc("library_overdue_loans", "Copies lent out past their due date.", nil, nil),
+	}
+}
+
+func (lc *loanCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lc.active
+	ch <- lc.overdue
+}
+
+func (lc *loanCollector) Collect(ch chan<- prometheus.Metric) {
+	active, overdue, err := lc.records.CountLoans(time.Now())
+	if err != nil {
+		lc.log.Error("Failed to count loans for metrics", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(lc.active, prometheus.GaugeValue, float64(active))
+	ch <- prometheus.MustNewConstMetric(lc.overdue, prometheus.GaugeValue, float64(overdue))
+}
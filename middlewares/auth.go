@@ -2,75 +2,89 @@ package middlewares
 
 import (
 	"fmt"
-	"library/initializers"
+	"library/apierror"
+	"library/config"
+	"library/logging"
 	"library/models"
-	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 )
 
-func CheckAuth(c *gin.Context) {
+// CheckAuth accepts access tokens signed with the configured secret whose session is still live
+func CheckAuth(db *gorm.DB, auth config.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
 
-	authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			apierror.Abort(c, apierror.TokenMissing)
+			return
+		}
 
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
+		authToken := strings.Split(authHeader, " ")
+		if len(authToken) != 2 || authToken[0] != "Bearer" {
+			apierror.Abort(c, apierror.TokenInvalid)
+			return
+		}
 
-	authToken := strings.Split(authHeader, " ")
-	if len(authToken) != 2 || authToken[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
+		tokenString := authToken[1]
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(auth.Secret), nil
+		})
+		if err != nil || !token.Valid {
+			apierror.Abort(c, apierror.TokenInvalid)
+			return
+		}
 
-	tokenString := authToken[1]
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			apierror.Abort(c, apierror.TokenInvalid)
+			return
 		}
-		return []byte(os.Getenv("SECRET")), nil
-	})
-	if err != nil || !token.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		c.Abort()
-		return
-	}
+		if float64(time.Now().Unix()) > claims["exp"].(float64) {
+			apierror.Abort(c, apierror.TokenExpired)
+			return
+		}
 
-	if float64(time.Now().Unix()) > claims["exp"].(float64) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "token expired"})
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
+		var user models.User
+		db.Where("ID=?", claims["id"]).Find(&user)
 
-	var user models.User
-	initializers.DB.Where("ID=?", claims["id"]).Find(&user)
+		if user.ID == 0 {
+			apierror.Abort(c, apierror.TokenInvalid)
+			return
+		}
 
-	if user.ID == 0 {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
+		// The session behind the token must still be live
+		sessionID, _ := claims["sid"].(float64)
+		var session models.Session
+		db.Where("id = ? AND user_id = ?", uint(sessionID), user.ID).Find(&session)
+		if session.ID == 0 || !session.Active(time.Now()) {
+			apierror.Abort(c, apierror.SessionRevoked)
+			return
+		}
 
-	var userResponse = models.UserResponse{
-		ID:       user.ID,
-		Nickname: user.Nickname,
-	}
+		role := user.Role
+		if role == "" {
+			role = models.RolePatron
+		}
 
-	c.Set("user", userResponse)
+		var userResponse = models.UserResponse{
+			ID:       user.ID,
+			Nickname: user.Nickname,
+			Role:     role,
+		}
 
-	c.Next()
+		c.Set("user", userResponse)
+		c.Set("session_id", session.ID)
+		c.Set(logging.UserIDKey, user.ID)
 
+		c.Next()
+	}
 }
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// When the /api/v1 routes replaced the POST-only RPC routes
+var rpcDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// Deprecated marks a route as superseded (RFC 9745) and links to the docs,
+// which name the /api/v1 route to use instead. The route keeps working.
+func Deprecated() gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", rpcDeprecatedAt.Unix())
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Link", `</docs>; rel="deprecation"; type="text/html"`)
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"errors"
+	"library/apierror"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler writes the error envelope for the last error a handler passed
+// to apierror.Abort. Any other error becomes a 500 so internals never leak.
+func ErrorHandler(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) {
+			logger.ErrorContext(c, "Unhandled error", "error", err)
+			apiErr = apierror.Internal("Internal server error")
+		}
+		c.JSON(apiErr.Status, gin.H{"error": apiErr})
+	}
+}
+
+// NoRoute answers unknown paths with the error envelope
+func NoRoute(c *gin.Context) {
+	apierror.Abort(c, apierror.RouteNotFound)
+}
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"library/apierror"
+	"library/models"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole lets the request through only when the authenticated user has one
+// of the given roles. It must run after CheckAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			apierror.Abort(c, apierror.Unauthorized)
+			return
+		}
+
+		userData, ok := user.(models.UserResponse)
+		if !ok || !slices.Contains(roles, userData.Role) {
+			apierror.Abort(c, apierror.Forbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
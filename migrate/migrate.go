@@ -1,42 +1,107 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"library/config"
 	"library/initializers"
-	"library/models"
-
+	"library/migrations"
 	"log"
+	"os"
+	"strconv"
+	"time"
 )
 
-func init() {
-	initializers.GetEnvs()
-	initializers.ConnectDB()
-}
+const usage = `Usage: go run migrate/migrate.go <command> [args]
+
+Commands:
+  status        list migrations and whether they are applied
+  up [n]        apply all pending migrations, or the next n
+  down [n]      roll back the last applied migration, or the last n
+  create <name> write an empty migration into the migrations directory
+
+Flags:
+  -config file  YAML config file, defaults to $CONFIG_FILE
+  -dir dir      where create writes migrations, defaults to migrations
+`
 
 func main() {
-	if initializers.DB == nil {
-		log.Fatal("Database connection is nil")
-	}
+	dir := flag.String("dir", "migrations", "directory new migrations are created in")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML config file")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
 
-	err := initializers.DB.AutoMigrate(&models.User{})
-	if err != nil {
-		log.Fatal("Failed to migrate User table:", err)
+	command := flag.Arg(0)
+	if command == "" {
+		command = "up"
 	}
 
-	err = initializers.DB.AutoMigrate(&models.BookType{})
-	if err != nil {
-		log.Fatal("Failed to migrate Book table:", err)
+	if command == "create" {
+		if flag.NArg() < 2 {
+			log.Fatal("Missing migration name")
+		}
+		path, err := migrations.Create(*dir, flag.Arg(1), time.Now())
+		if err != nil {
+			log.Fatal("Failed to create migration:", err)
+		}
+		log.Printf("Created %s\n", path)
+		return
 	}
 
-	err = initializers.DB.AutoMigrate(&models.Book{})
-	if err != nil {
-		log.Fatal("Failed to migrate Record table:", err)
+	steps := 0
+	if flag.NArg() > 1 {
+		var err error
+		if steps, err = strconv.Atoi(flag.Arg(1)); err != nil || steps < 0 {
+			log.Fatal("Invalid number of steps:", flag.Arg(1))
+		}
 	}
 
-	err = initializers.DB.AutoMigrate(&models.Record{})
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Fatal("Failed to migrate Record table:", err)
+		log.Fatal("Invalid config: ", err)
+	}
+	initializers.ConnectDB(cfg.Database)
+	if initializers.DB == nil {
+		log.Fatal("Database connection is nil")
 	}
 
+	switch command {
+	case "status":
+		statuses, err := migrations.Status(initializers.DB)
+		if err != nil {
+			log.Fatal("Failed to read migration status:", err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s  %-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+	case "up":
+		applied, err := migrations.Up(initializers.DB, steps)
+		for _, migration := range applied {
+			log.Printf("Applied %s_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to migrate:", err)
+		}
+		if len(applied) == 0 {
+			log.Println("Nothing to migrate")
+		}
+	case "down":
+		rolledBack, err := migrations.Down(initializers.DB, steps)
+		for _, migration := range rolledBack {
+			log.Printf("Rolled back %s_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to roll back:", err)
+		}
+		if len(rolledBack) == 0 {
+			log.Println("Nothing to roll back")
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
 }
-
-//go mod migrate/migrate.go
@@ -0,0 +1,202 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The baseline is the schema AutoMigrate used to build. It is idempotent so
+// databases created before versioned migrations can adopt it in place.
+func init() {
+	type CommonTime struct {
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		DeletedAt time.Time
+	}
+	type User struct {
+		ID       uint   `gorm:"primary_key"`
+		Username string `gorm:"unique"`
+		Password string
+		Nickname string
+		Role     string `gorm:"default:patron"`
+		Category string `gorm:"default:standard"`
+		CommonTime
+	}
+	type Author struct {
+		ID   uint   `gorm:"primary_key"`
+		Name string `gorm:"uniqueIndex"`
+		CommonTime
+	}
+	type Subject struct {
+		ID      uint   `gorm:"primary_key"`
+		Heading string `gorm:"uniqueIndex"`
+		CommonTime
+	}
+	type BookType struct {
+		ID              uint `gorm:"primary_key"`
+		Title           string
+		ISBN10          string `gorm:"column:isbn10;index"`
+		ISBN13          string `gorm:"column:isbn13;index"`
+		Publisher       string
+		PublicationYear int
+		Language        string
+		PageCount       int
+		Description     string     `gorm:"type:text"`
+		ItemType        string     `gorm:"default:book"`
+		Authors         []Author   `gorm:"many2many:book_type_authors"`
+		Subjects        []Subject  `gorm:"many2many:book_type_subjects"`
+		RetiredAt       *time.Time `gorm:"index"`
+		CommonTime
+	}
+	type Book struct {
+		ID         uint `gorm:"primary_key"`
+		BookTypeID uint
+		Barcode    *string  `gorm:"uniqueIndex;size:64"`
+		Status     uint     `gorm:"default:1"`
+		BookType   BookType `gorm:"foreignKey:BookTypeID"`
+		CommonTime
+	}
+	type Record struct {
+		ID           uint
+		UserID       uint
+		BookID       uint `gorm:"uniqueIndex:idx_records_open_book,where:is_closed = false"`
+		ReturnedAt   *time.Time
+		DueAt        time.Time
+		RenewalCount int  `gorm:"default:0"`
+		User         User `gorm:"foreignKey:UserID"`
+		Book         Book `gorm:"foreignKey:BookID"`
+		IsClosed     bool `gorm:"column:is_closed;default:false"`
+		CommonTime
+	}
+	type Hold struct {
+		ID         uint `gorm:"primary_key"`
+		UserID     uint
+		BookTypeID uint
+		BookID     *uint
+		Status     uint `gorm:"default:1"`
+		ReadyAt    *time.Time
+		ExpiresAt  *time.Time
+		User       User     `gorm:"foreignKey:UserID"`
+		BookType   BookType `gorm:"foreignKey:BookTypeID"`
+		CommonTime
+	}
+	type Fine struct {
+		ID          uint `gorm:"primary_key"`
+		UserID      uint
+		RecordID    uint
+		Amount      int64
+		Paid        int64
+		Waived      int64
+		DaysOverdue int
+		Status      uint `gorm:"default:1"`
+		WaivedByID  *uint
+		Note        string
+		User        User   `gorm:"foreignKey:UserID"`
+		Record      Record `gorm:"foreignKey:RecordID"`
+		CommonTime
+	}
+	type FinePayment struct {
+		ID           uint `gorm:"primary_key"`
+		UserID       uint
+		Amount       int64
+		ReceivedByID uint
+		Note         string
+		CommonTime
+	}
+	type LoanPolicy struct {
+		ID             uint   `gorm:"primary_key"`
+		PatronCategory string `gorm:"uniqueIndex:idx_loan_policy_scope"`
+		ItemType       string `gorm:"uniqueIndex:idx_loan_policy_scope"`
+		LoanDays       int
+		RenewalDays    int
+		MaxRenewals    int
+		MaxLoans       int
+		MaxHolds       int
+		CommonTime
+	}
+	type Session struct {
+		ID         uint `gorm:"primary_key"`
+		UserID     uint `gorm:"index"`
+		UserAgent  string
+		ExpiresAt  time.Time
+		LastUsedAt time.Time
+		RevokedAt  *time.Time
+		User       User `gorm:"foreignKey:UserID"`
+		CommonTime
+	}
+	type RefreshToken struct {
+		ID        uint   `gorm:"primary_key"`
+		SessionID uint   `gorm:"index"`
+		TokenHash string `gorm:"uniqueIndex;size:64"`
+		UsedAt    *time.Time
+		Session   Session `gorm:"foreignKey:SessionID"`
+		CommonTime
+	}
+
+	register(Migration{
+		Version: "20261018000000",
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&User{}, &Author{}, &Subject{}, &BookType{}, &Book{}); err != nil {
+				return err
+			}
+
+			// Give copies created before barcodes existed a default one
+			var books []Book
+			if err := tx.Select("id").Where("barcode IS NULL").Find(&books).Error; err != nil {
+				return err
+			}
+			for _, book := range books {
+				if err := tx.Model(&Book{}).
+					Where("id = ?", book.ID).
+					Update("barcode", fmt.Sprintf("LIB%08d", book.ID)).Error; err != nil {
+					return fmt.Errorf("backfill barcode of book %d: %w", book.ID, err)
+				}
+			}
+
+			// A copy lent twice before the open record index existed would
+			// stop it from building: keep the oldest open record of each copy,
+			// close the others and mark the copy as on loan
+			if tx.Migrator().HasTable(&Record{}) && tx.Migrator().HasColumn(&Record{}, "IsClosed") {
+				var duplicates []struct {
+					BookID uint
+					KeepID uint
+				}
+				if err := tx.Model(&Record{}).
+					Select("book_id, MIN(id) AS keep_id").
+					Where("is_closed = ?", false).
+					Group("book_id").
+					Having("COUNT(*) > 1").
+					Scan(&duplicates).Error; err != nil {
+					return fmt.Errorf("find copies lent twice: %w", err)
+				}
+				now := time.Now()
+				for _, duplicate := range duplicates {
+					if err := tx.Model(&Record{}).
+						Where("book_id = ? AND is_closed = ? AND id <> ?", duplicate.BookID, false, duplicate.KeepID).
+						UpdateColumns(map[string]interface{}{"is_closed": true, "returned_at": now}).Error; err != nil {
+						return fmt.Errorf("close duplicate records of book %d: %w", duplicate.BookID, err)
+					}
+					if err := tx.Model(&Book{}).Where("id = ?", duplicate.BookID).Update("status", 2).Error; err != nil {
+						return fmt.Errorf("fix status of book %d: %w", duplicate.BookID, err)
+					}
+				}
+			}
+
+			return tx.AutoMigrate(&Record{}, &Hold{}, &Fine{}, &FinePayment{}, &LoanPolicy{}, &Session{}, &RefreshToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				&RefreshToken{}, &Session{},
+				&LoanPolicy{},
+				&FinePayment{}, &Fine{},
+				&Hold{}, &Record{},
+				&Book{}, "book_type_authors", "book_type_subjects", &BookType{},
+				&Subject{}, &Author{},
+				&User{},
+			)
+		},
+	})
+}
@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// deleted_at used to be a plain timestamp written as the zero time on every
+// row. It becomes a nullable soft delete column, and retired titles and
+// withdrawn copies become soft deleted rows.
+func init() {
+	type BookType struct {
+		ID        uint
+		RetiredAt *time.Time `gorm:"index"`
+	}
+
+	tables := []string{
+		"users", "authors", "subjects", "book_types", "books", "records",
+		"holds", "fines", "fine_payments", "loan_policies", "sessions", "refresh_tokens",
+	}
+	// Anything before this is the zero time the old models wrote
+	zeroBefore := time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	register(Migration{
+		Version: "20261018000001",
+		Name:    "soft_delete",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range tables {
+				if err := tx.Exec("UPDATE "+table+" SET deleted_at = NULL WHERE deleted_at < ?", zeroBefore).Error; err != nil {
+					return fmt.Errorf("clear %s.deleted_at: %w", table, err)
+				}
+				if err := tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_deleted_at ON %s (deleted_at)", table, table)).Error; err != nil {
+					return fmt.Errorf("index %s.deleted_at: %w", table, err)
+				}
+			}
+
+			if err := tx.Exec("UPDATE book_types SET deleted_at = retired_at WHERE retired_at IS NOT NULL").Error; err != nil {
+				return err
+			}
+			// Copies of retired titles go with them, other withdrawn copies when they were withdrawn
+			if err := tx.Exec(`UPDATE books SET deleted_at = (SELECT retired_at FROM book_types WHERE book_types.id = books.book_type_id)
+				WHERE status = 4 AND book_type_id IN (SELECT id FROM book_types WHERE retired_at IS NOT NULL)`).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE books SET deleted_at = updated_at WHERE status = 4 AND deleted_at IS NULL").Error; err != nil {
+				return err
+			}
+
+			if err := tx.Migrator().DropIndex(&BookType{}, "idx_book_types_retired_at"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&BookType{}, "retired_at")
+		},
+		// Deleted users and records show up again, the old schema had no way to hide them
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&BookType{}, "RetiredAt"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(&BookType{}, "RetiredAt"); err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE book_types SET retired_at = deleted_at WHERE deleted_at IS NOT NULL").Error; err != nil {
+				return err
+			}
+
+			for _, table := range tables {
+				if err := tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS idx_%s_deleted_at", table)).Error; err != nil {
+					return fmt.Errorf("drop index on %s.deleted_at: %w", table, err)
+				}
+				if err := tx.Exec("UPDATE "+table+" SET deleted_at = ? WHERE deleted_at IS NULL", time.Time{}).Error; err != nil {
+					return fmt.Errorf("reset %s.deleted_at: %w", table, err)
+				}
+			}
+			return nil
+		},
+	})
+}
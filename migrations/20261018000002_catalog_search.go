@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// Postgres keeps a weighted tsvector of each title's title, authors, subjects
+// and description for full-text search, plus the plain text of the first
+// three for trigram typo matching. Triggers refresh both when a title or its
+// links change. Other databases search an index built in memory instead.
+func init() {
+	up := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE book_types
+			ADD COLUMN IF NOT EXISTS search_vector tsvector,
+			ADD COLUMN IF NOT EXISTS search_text text NOT NULL DEFAULT ''`,
+		`CREATE OR REPLACE FUNCTION book_type_search_refresh(target bigint) RETURNS void AS $$
+			UPDATE book_types SET
+				search_vector =
+					setweight(to_tsvector('simple', coalesce(book_types.title, '')), 'A') ||
+					setweight(to_tsvector('simple', coalesce(a.names, '')), 'B') ||
+					setweight(to_tsvector('simple', coalesce(s.headings, '')), 'C') ||
+					setweight(to_tsvector('simple', coalesce(book_types.description, '')), 'D'),
+				search_text = concat_ws(' ', book_types.title, a.names, s.headings)
+			FROM
+				(SELECT string_agg(authors.name, ' ') AS names FROM book_type_authors
+					JOIN authors ON authors.id = book_type_authors.author_id
+					WHERE book_type_authors.book_type_id = target) a,
+				(SELECT string_agg(subjects.heading, ' ') AS headings FROM book_type_subjects
+					JOIN subjects ON subjects.id = book_type_subjects.subject_id
+					WHERE book_type_subjects.book_type_id = target) s
+			WHERE book_types.id = target
+		$$ LANGUAGE sql`,
+		`CREATE OR REPLACE FUNCTION book_types_search_trigger() RETURNS trigger AS $$
+		BEGIN
+			PERFORM book_type_search_refresh(NEW.id);
+			RETURN NULL;
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION book_type_links_search_trigger() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				PERFORM book_type_search_refresh(OLD.book_type_id);
+			ELSE
+				PERFORM book_type_search_refresh(NEW.book_type_id);
+			END IF;
+			RETURN NULL;
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE TRIGGER book_types_search AFTER INSERT OR UPDATE OF title, description ON book_types
+			FOR EACH ROW EXECUTE FUNCTION book_types_search_trigger()`,
+		`CREATE TRIGGER book_type_authors_search AFTER INSERT OR DELETE ON book_type_authors
+			FOR EACH ROW EXECUTE FUNCTION book_type_links_search_trigger()`,
+		`CREATE TRIGGER book_type_subjects_search AFTER INSERT OR DELETE ON book_type_subjects
+			FOR EACH ROW EXECUTE FUNCTION book_type_links_search_trigger()`,
+		`SELECT book_type_search_refresh(id) FROM book_types`,
+		`CREATE INDEX IF NOT EXISTS idx_book_types_search_vector ON book_types USING gin (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_book_types_search_text ON book_types USING gin (search_text gin_trgm_ops)`,
+	}
+	// pg_trgm stays, other databases may use it
+	down := []string{
+		`DROP TRIGGER IF EXISTS book_type_subjects_search ON book_type_subjects`,
+		`DROP TRIGGER IF EXISTS book_type_authors_search ON book_type_authors`,
+		`DROP TRIGGER IF EXISTS book_types_search ON book_types`,
+		`DROP FUNCTION IF EXISTS book_type_links_search_trigger()`,
+		`DROP FUNCTION IF EXISTS book_types_search_trigger()`,
+		`DROP FUNCTION IF EXISTS book_type_search_refresh(bigint)`,
+		`DROP INDEX IF EXISTS idx_book_types_search_text`,
+		`DROP INDEX IF EXISTS idx_book_types_search_vector`,
+		`ALTER TABLE book_types DROP COLUMN IF EXISTS search_text, DROP COLUMN IF EXISTS search_vector`,
+	}
+
+	register(Migration{
+		Version: "20261018000002",
+		Name:    "catalog_search",
+		Up: func(tx *gorm.DB) error {
+			return execPostgres(tx, up)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execPostgres(tx, down)
+		},
+	})
+}
+
+// execPostgres runs statements on Postgres and does nothing elsewhere
+func execPostgres(tx *gorm.DB, statements []string) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// Copies remember the shelf they are kept on, shown on the title's detail
+// page. Existing copies start without one.
+func init() {
+	type Book struct {
+		ShelfLocation string `gorm:"size:64"`
+	}
+
+	register(Migration{
+		Version: "20261018000003",
+		Name:    "shelf_location",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&Book{}, "ShelfLocation") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&Book{}, "ShelfLocation")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&Book{}, "ShelfLocation")
+		},
+	})
+}
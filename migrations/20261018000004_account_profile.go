@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Patrons keep an email address and phone number on their account, and
+// changes they make to it are recorded in audit_entries.
+func init() {
+	type User struct {
+		Email string `gorm:"size:254"`
+		Phone string `gorm:"size:32"`
+	}
+	type AuditEntry struct {
+		ID        uint `gorm:"primary_key"`
+		UserID    uint `gorm:"index"`
+		ActorID   uint
+		Action    string `gorm:"size:32"`
+		Fields    string
+		RequestID string `gorm:"size:64"`
+		CreatedAt time.Time
+	}
+
+	register(Migration{
+		Version: "20261018000004",
+		Name:    "account_profile",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"Email", "Phone"} {
+				if tx.Migrator().HasColumn(&User{}, column) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&User{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.AutoMigrate(&AuditEntry{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&AuditEntry{}); err != nil {
+				return err
+			}
+			for _, column := range []string{"Phone", "Email"} {
+				if err := tx.Migrator().DropColumn(&User{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// Renaming an author or subject refreshes the search columns of every title
+// linked to it; catalog_search only watched the titles and their links.
+func init() {
+	up := []string{
+		`CREATE OR REPLACE FUNCTION authors_search_trigger() RETURNS trigger AS $$
+		BEGIN
+			PERFORM book_type_search_refresh(book_type_id) FROM book_type_authors WHERE author_id = NEW.id;
+			RETURN NULL;
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION subjects_search_trigger() RETURNS trigger AS $$
+		BEGIN
+			PERFORM book_type_search_refresh(book_type_id) FROM book_type_subjects WHERE subject_id = NEW.id;
+			RETURN NULL;
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE TRIGGER authors_search AFTER UPDATE OF name ON authors
+			FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name) EXECUTE FUNCTION authors_search_trigger()`,
+		`CREATE TRIGGER subjects_search AFTER UPDATE OF heading ON subjects
+			FOR EACH ROW WHEN (OLD.heading IS DISTINCT FROM NEW.heading) EXECUTE FUNCTION subjects_search_trigger()`,
+	}
+	down := []string{
+		`DROP TRIGGER IF EXISTS subjects_search ON subjects`,
+		`DROP TRIGGER IF EXISTS authors_search ON authors`,
+		`DROP FUNCTION IF EXISTS subjects_search_trigger()`,
+		`DROP FUNCTION IF EXISTS authors_search_trigger()`,
+	}
+
+	register(Migration{
+		Version: "20261018000005",
+		Name:    "search_renames",
+		Up: func(tx *gorm.DB) error {
+			return execPostgres(tx, up)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execPostgres(tx, down)
+		},
+	})
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// Brings the schema the earlier migrations leave in line with the models.
+// Copies no longer default to a status, the code always sets one. SQLite
+// drops a column by rebuilding the table without its indexes, so the ones
+// soft_delete, shelf_location and account_profile lose that way are built
+// again; on Postgres they are all there already.
+func init() {
+	type User struct {
+		DeletedAt gorm.DeletedAt `gorm:"index"`
+	}
+	type BookType struct {
+		ISBN10    string         `gorm:"column:isbn10;index"`
+		ISBN13    string         `gorm:"column:isbn13;index"`
+		DeletedAt gorm.DeletedAt `gorm:"index"`
+	}
+	type Book struct {
+		Barcode   *string `gorm:"uniqueIndex;size:64"`
+		Status    uint
+		DeletedAt gorm.DeletedAt `gorm:"index"`
+	}
+
+	indexes := []struct {
+		model  any
+		fields []string
+	}{
+		{&User{}, []string{"DeletedAt"}},
+		{&BookType{}, []string{"ISBN10", "ISBN13", "DeletedAt"}},
+		{&Book{}, []string{"Barcode", "DeletedAt"}},
+	}
+
+	register(Migration{
+		Version: "20261018000006",
+		Name:    "schema_drift",
+		Up: func(tx *gorm.DB) error {
+			// Rebuilds books on SQLite, so it goes before the indexes
+			if tx.Dialector.Name() == "postgres" {
+				if err := tx.Exec("ALTER TABLE books ALTER COLUMN status DROP DEFAULT").Error; err != nil {
+					return err
+				}
+			} else if err := tx.Migrator().AlterColumn(&Book{}, "Status"); err != nil {
+				return err
+			}
+
+			for _, index := range indexes {
+				for _, field := range index.fields {
+					if tx.Migrator().HasIndex(index.model, field) {
+						continue
+					}
+					if err := tx.Migrator().CreateIndex(index.model, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		// Nothing to undo: the default went unused and the indexes belong to
+		// the migrations before this one
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
@@ -0,0 +1,196 @@
+// Package migrations holds the versioned schema changes of the library
+// database and the runner that applies them.
+//
+// Every migration lives in its own file named after its version and
+// registers itself from init. Migrations describe the schema with structs
+// frozen at the time they were written, never with the live models, so
+// later model changes cannot rewrite history.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockKey identifies the advisory lock held while migrating
+const lockKey = 727134901
+
+type Migration struct {
+	Version string // timestamp, also the sort key
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is one applied migration
+type SchemaMigration struct {
+	Version   string `gorm:"primaryKey;size:14"`
+	Name      string
+	AppliedAt time.Time
+}
+
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	AppliedAt *time.Time
+}
+
+var registry []Migration
+
+func register(migration Migration) {
+	registry = append(registry, migration)
+}
+
+// All returns the registered migrations in version order
+func All() []Migration {
+	migrations := make([]Migration, len(registry))
+	copy(migrations, registry)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// Status lists every known migration and when it was applied
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range All() {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if schemaMigration, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &schemaMigration.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up applies pending migrations in order, at most steps of them or all when steps is 0
+func Up(db *gorm.DB, steps int) ([]Migration, error) {
+	var done []Migration
+	err := withLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range All() {
+			if steps > 0 && len(done) == steps {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %s_%s up: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the most recently applied migrations, steps of them or one when steps is 0
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var done []Migration
+	err := withLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		migrations := All()
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("migration %s_%s cannot be rolled back", migration.Version, migration.Name)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Down(tx); err != nil {
+					return err
+				}
+				return tx.Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %s_%s down: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func appliedMigrations(db *gorm.DB) (map[string]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	var schemaMigrations []SchemaMigration
+	if err := db.Find(&schemaMigrations).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[string]SchemaMigration, len(schemaMigrations))
+	for _, schemaMigration := range schemaMigrations {
+		applied[schemaMigration.Version] = schemaMigration
+	}
+	return applied, nil
+}
+
+// withLock runs fn on a single connection holding the migration lock, so two
+// deploys migrating at once take turns. Only Postgres needs an explicit lock;
+// SQLite instead needs foreign keys off, since it alters a column by copying
+// the table into a new one and dropping the old.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		// Calls chained on the raw connection would share one statement
+		conn = conn.Session(&gorm.Session{NewDB: true})
+		switch conn.Dialector.Name() {
+		case "postgres":
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+				return fmt.Errorf("acquire migration lock: %w", err)
+			}
+			err := fn(conn)
+			if unlockErr := conn.Exec("SELECT pg_advisory_unlock(?)", lockKey).Error; unlockErr != nil {
+				err = errors.Join(err, fmt.Errorf("release migration lock: %w", unlockErr))
+			}
+			return err
+		case "sqlite":
+			if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+				return fmt.Errorf("disable foreign keys: %w", err)
+			}
+			err := fn(conn)
+			if fkErr := conn.Exec("PRAGMA foreign_keys = ON").Error; fkErr != nil {
+				err = errors.Join(err, fmt.Errorf("enable foreign keys: %w", fkErr))
+			}
+			return err
+		default:
+			return fn(conn)
+		}
+	})
+}
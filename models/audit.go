@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AuditEntry records a change to an account. It names the fields that
+// changed, never their values, and stays after the account is deleted.
+type AuditEntry struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    uint      `json:"user_id" gorm:"index"` // account that changed
+	ActorID   uint      `json:"actor_id"`             // user who changed it
+	Action    string    `json:"action" gorm:"size:32"`
+	Fields    string    `json:"fields"` // comma separated, e.g. "email,phone"
+	RequestID string    `json:"request_id" gorm:"size:64"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+type Author struct {
+	ID   uint   `json:"id" gorm:"primary_key"`
+	Name string `json:"name" gorm:"uniqueIndex"`
+	CommonTime
+}
+
+type Subject struct {
+	ID      uint   `json:"id" gorm:"primary_key"`
+	Heading string `json:"heading" gorm:"uniqueIndex"`
+	CommonTime
+}
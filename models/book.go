@@ -1,38 +1,200 @@
 package models
 
+import (
+	"fmt"
+	"time"
+)
+
 type BookType struct {
-	ID    uint   `json:"id" gorm:"primary_key"`
-	Title string `json:"title"`
+	ID              uint      `json:"id" gorm:"primary_key"`
+	Title           string    `json:"title"`
+	ISBN10          string    `json:"isbn10" gorm:"column:isbn10;index"`
+	ISBN13          string    `json:"isbn13" gorm:"column:isbn13;index"`
+	Publisher       string    `json:"publisher"`
+	PublicationYear int       `json:"publication_year"`
+	Language        string    `json:"language"` // ISO 639-1 code, e.g. "en"
+	PageCount       int       `json:"page_count"`
+	Description     string    `json:"description" gorm:"type:text"`
+	ItemType        string    `json:"item_type" gorm:"default:book"` // item type used to pick a loan policy
+	Authors         []Author  `json:"authors" gorm:"many2many:book_type_authors"`
+	Subjects        []Subject `json:"subjects" gorm:"many2many:book_type_subjects"`
 	CommonTime
 }
 
 type Book struct {
-	ID         uint     `json:"id" gorm:"primary_key"`
-	BookTypeID uint     `josn:"book_type_id"`
-	Status     uint     `json:"status"` //1: avaiable, 2: rent out
-	BookType   BookType `gorm:"foreignKey:BookTypeID"`
+	ID            uint     `json:"id" gorm:"primary_key"`
+	BookTypeID    uint     `json:"book_type_id"`
+	Barcode       *string  `json:"barcode" gorm:"uniqueIndex;size:64"`
+	Status        uint     `json:"status"`                        //1: avaiable, 2: rent out, 3: on hold shelf, 4: withdrawn
+	ShelfLocation string   `json:"shelf_location" gorm:"size:64"` // where the copy is shelved, e.g. "2F Fiction A-C"
+	BookType      BookType `gorm:"foreignKey:BookTypeID"`
 	CommonTime
 }
 
+// CopyResponse is one copy of a title on its detail page
+type CopyResponse struct {
+	ID            uint       `json:"id"`
+	Barcode       string     `json:"barcode"`
+	Status        string     `json:"status"`
+	ShelfLocation string     `json:"shelf_location"`
+	DueAt         *time.Time `json:"due_at"` // set while the copy is on loan
+}
+
+// BookDetailResponse is a title with its copies. NextDueAt is the earliest
+// due date of its copies on loan, for "expected back on".
+type BookDetailResponse struct {
+	BookResponse
+	ItemType        string         `json:"item_type"`
+	Copies          []CopyResponse `json:"copies"`
+	NextDueAt       *time.Time     `json:"next_due_at"`
+	HoldQueueLength int64          `json:"hold_queue_length"` // holds waiting for a copy
+}
+
 type BookResponse struct {
-	ID             uint   `json:"id" gorm:"primary_key"`
-	Name           string `json:"name"`
-	TotalCount     int    `json:"total_count"`
-	AvailableCount int    `json:"available_count"`
+	ID              uint     `json:"id" gorm:"primary_key"`
+	Name            string   `json:"name"`
+	Authors         []string `json:"authors"`
+	ISBN10          string   `json:"isbn10"`
+	ISBN13          string   `json:"isbn13"`
+	Publisher       string   `json:"publisher"`
+	PublicationYear int      `json:"publication_year"`
+	Language        string   `json:"language"`
+	PageCount       int      `json:"page_count"`
+	Description     string   `json:"description"`
+	Subjects        []string `json:"subjects"`
+	TotalCount      int      `json:"total_count"`
+	AvailableCount  int      `json:"available_count"`
 }
 type BookIDsPayload struct {
 	BookTypeIDs []uint `json:"ids"`
 }
+type BookTypePayload struct {
+	ID              uint     `json:"id"`
+	Title           string   `json:"title" binding:"required"`
+	Authors         []string `json:"authors"`
+	ISBN10          string   `json:"isbn10"`
+	ISBN13          string   `json:"isbn13"`
+	Publisher       string   `json:"publisher"`
+	PublicationYear int      `json:"publication_year" binding:"min=0"`
+	Language        string   `json:"language" binding:"omitempty,len=2"`
+	PageCount       int      `json:"page_count" binding:"min=0"`
+	Description     string   `json:"description"`
+	Subjects        []string `json:"subjects"`
+	ItemType        string   `json:"item_type"`
+	Copies          int      `json:"copies" binding:"min=0,max=100"` // copies to add when creating the title
+}
+type BookCopiesPayload struct {
+	BookTypeID uint     `json:"book_type_id" binding:"required"`
+	Count      int      `json:"count" binding:"required,min=1,max=100"`
+	Barcodes   []string `json:"barcodes"` // optional, one per copy; generated when empty
+	// ShelfLocation is where the new copies are shelved
+	ShelfLocation string `json:"shelf_location" binding:"max=64"`
+}
+type BookCopyMovePayload struct {
+	ID            uint   `json:"id" binding:"required"`
+	ShelfLocation string `json:"shelf_location" binding:"max=64"`
+}
+type BookCopyPayload struct {
+	ID uint `json:"id" binding:"required"`
+}
 type BookRequest struct {
-	Title string `json:"title"`
+	Title     string `json:"title" form:"title"`
+	Author    string `json:"author" form:"author"`
+	ISBN      string `json:"isbn" form:"isbn"` // matches either ISBN-10 or ISBN-13
+	Publisher string `json:"publisher" form:"publisher"`
+	Language  string `json:"language" form:"language"`
+	Subject   string `json:"subject" form:"subject"`
+	YearFrom  int    `json:"year_from" form:"year_from"`
+	YearTo    int    `json:"year_to" form:"year_to"`
+	Available bool   `json:"available" form:"available"` // only titles with a copy on the shelf
+	// Sort orders by id (default), title, newest (latest added first),
+	// popular (most borrowed first) or available (most copies on the shelf first)
+	Sort string `json:"sort" form:"sort" binding:"omitempty,oneof=id title newest popular available"`
+	// Cursor continues from next_cursor of the previous page in place of page
+	Cursor string `json:"cursor" form:"cursor"`
 	Pagination
 }
 
 func (bt *BookType) ToResponse(totalCount, availableCount int) BookResponse {
+	var authors []string
+	for _, author := range bt.Authors {
+		authors = append(authors, author.Name)
+	}
+	var subjects []string
+	for _, subject := range bt.Subjects {
+		subjects = append(subjects, subject.Heading)
+	}
+
 	return BookResponse{
-		ID:             bt.ID,
-		Name:           bt.Title,
-		TotalCount:     totalCount,
-		AvailableCount: availableCount,
+		ID:              bt.ID,
+		Name:            bt.Title,
+		Authors:         authors,
+		ISBN10:          bt.ISBN10,
+		ISBN13:          bt.ISBN13,
+		Publisher:       bt.Publisher,
+		PublicationYear: bt.PublicationYear,
+		Language:        bt.Language,
+		PageCount:       bt.PageCount,
+		Description:     bt.Description,
+		Subjects:        subjects,
+		TotalCount:      totalCount,
+		AvailableCount:  availableCount,
 	}
 }
+
+// ToDetailResponse describes the title with its copies, the due dates of
+// those on loan and the number of waiting holds
+func (bt *BookType) ToDetailResponse(copies []Book, dueDates map[uint]time.Time, holdQueueLength int64) BookDetailResponse {
+	response := BookDetailResponse{
+		ItemType:        bt.ItemType,
+		Copies:          make([]CopyResponse, 0, len(copies)),
+		HoldQueueLength: holdQueueLength,
+	}
+	var available int
+	for _, book := range copies {
+		var dueAt *time.Time
+		if due, ok := dueDates[book.ID]; ok {
+			dueAt = &due
+			if response.NextDueAt == nil || due.Before(*response.NextDueAt) {
+				response.NextDueAt = dueAt
+			}
+		}
+		if book.Status == 1 {
+			available++
+		}
+		response.Copies = append(response.Copies, book.ToCopyResponse(dueAt))
+	}
+	response.BookResponse = bt.ToResponse(len(copies), available)
+	return response
+}
+
+func (b *Book) ToCopyResponse(dueAt *time.Time) CopyResponse {
+	var status string
+	switch b.Status {
+	case 1:
+		status = "Available"
+	case 2:
+		status = "On loan"
+	case 3:
+		status = "On hold shelf"
+	default:
+		status = "Withdrawn"
+	}
+	var barcode string
+	if b.Barcode != nil {
+		barcode = *b.Barcode
+	}
+
+	return CopyResponse{
+		ID:            b.ID,
+		Barcode:       barcode,
+		Status:        status,
+		ShelfLocation: b.ShelfLocation,
+		DueAt:         dueAt,
+	}
+}
+
+// DefaultBarcode is the barcode given to copies added without one
+func DefaultBarcode(bookID uint) string {
+	return fmt.Sprintf("LIB%08d", bookID)
+}
@@ -1,14 +1,43 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
 
 type Pagination struct {
-	Page     int `json:"page"`
-	PageSize int `json:"page_size"`
+	Page     int `json:"page" form:"page"`
+	PageSize int `json:"page_size" form:"page_size"` // DefaultPageSize when 0, at most MaxPageSize
+}
+
+// Limit is the page size actually served
+func (p Pagination) Limit() int {
+	if p.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	return min(p.PageSize, MaxPageSize)
+}
+
+func (p Pagination) Offset() int {
+	return max(p.Page, 0) * p.Limit()
+}
+
+// PageInfo tells a client how much of a list it has seen. NextCursor picks up
+// after the last row of the page and is empty on the last page.
+type PageInfo struct {
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
 }
 
 type CommonTime struct {
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	DeletedAt time.Time `json:"deleted_at"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"` // soft delete, hidden from default queries
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// NormalizeEmail trims the address and lower-cases its domain
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	if at := strings.LastIndexByte(email, '@'); at >= 0 {
+		email = email[:at] + strings.ToLower(email[at:])
+	}
+	return email
+}
+
+// ValidEmail accepts a bare address, without a display name
+func ValidEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// NormalizePhone strips spaces, hyphens, dots and brackets
+func NormalizePhone(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(phone)
+}
+
+// ValidPhone checks a normalized number is 6 to 15 digits, with an optional leading +
+func ValidPhone(phone string) bool {
+	digits := strings.TrimPrefix(phone, "+")
+	if len(digits) < 6 || len(digits) > 15 {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
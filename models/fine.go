@@ -0,0 +1,113 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Amounts are stored in cents
+type Fine struct {
+	ID          uint   `json:"id" gorm:"primary_key"`
+	UserID      uint   `json:"user_id"`
+	RecordID    uint   `json:"record_id"`
+	Amount      int64  `json:"amount"`
+	Paid        int64  `json:"paid"`
+	Waived      int64  `json:"waived"`
+	DaysOverdue int    `json:"days_overdue"`
+	Status      uint   `json:"status" gorm:"default:1"` //1: outstanding, 2: paid, 3: waived
+	WaivedByID  *uint  `json:"waived_by_id"`
+	Note        string `json:"note"`
+	User        User   `gorm:"foreignKey:UserID"`
+	Record      Record `gorm:"foreignKey:RecordID"`
+	CommonTime
+}
+
+type FinePayment struct {
+	ID           uint   `json:"id" gorm:"primary_key"`
+	UserID       uint   `json:"user_id"`
+	Amount       int64  `json:"amount"`
+	ReceivedByID uint   `json:"received_by_id"`
+	Note         string `json:"note"`
+	CommonTime
+}
+
+type FinePolicy struct {
+	DailyRate      int64 // charged per day overdue after the grace period
+	GraceDays      int   // days after DueAt that are not charged
+	MaxAmount      int64 // cap per record, 0 for no cap
+	BlockThreshold int64 // outstanding balance above which borrowing and extending is refused
+}
+
+type FineResponse struct {
+	ID          uint      `json:"id"`
+	RecordID    uint      `json:"record_id"`
+	Title       string    `json:"title"`
+	Amount      int64     `json:"amount"`
+	Paid        int64     `json:"paid"`
+	Waived      int64     `json:"waived"`
+	Outstanding int64     `json:"outstanding"`
+	DaysOverdue int       `json:"days_overdue"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type FineSearchRequest struct {
+	Status int `json:"status"` //0: all, 1: outstanding, 2: settled
+	Pagination
+}
+
+type FinePaymentRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Amount int64  `json:"amount" binding:"required,gt=0"`
+	Note   string `json:"note"`
+}
+
+type FineWaiveRequest struct {
+	IDs  []uint `json:"ids"`
+	Note string `json:"note"`
+}
+
+// Assess works out the charge for a loan returned at returnedAt
+func (p FinePolicy) Assess(dueAt, returnedAt time.Time) (amount int64, daysOverdue int) {
+	if !returnedAt.After(dueAt) {
+		return 0, 0
+	}
+	daysOverdue = int(math.Ceil(returnedAt.Sub(dueAt).Hours() / 24))
+	chargedDays := daysOverdue - p.GraceDays
+	if chargedDays <= 0 {
+		return 0, daysOverdue
+	}
+
+	amount = int64(chargedDays) * p.DailyRate
+	if p.MaxAmount > 0 && amount > p.MaxAmount {
+		amount = p.MaxAmount
+	}
+	return amount, daysOverdue
+}
+
+func (f *Fine) Outstanding() int64 {
+	return f.Amount - f.Paid - f.Waived
+}
+
+func (f *Fine) ToResponse() FineResponse {
+	var status = "Outstanding"
+	switch f.Status {
+	case 2:
+		status = "Paid"
+	case 3:
+		status = "Waived"
+	}
+
+	return FineResponse{
+		ID:          f.ID,
+		RecordID:    f.RecordID,
+		Title:       f.Record.Book.BookType.Title,
+		Amount:      f.Amount,
+		Paid:        f.Paid,
+		Waived:      f.Waived,
+		Outstanding: f.Outstanding(),
+		DaysOverdue: f.DaysOverdue,
+		Status:      status,
+		CreatedAt:   f.CreatedAt,
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+type Hold struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	UserID     uint       `json:"user_id"`
+	BookTypeID uint       `json:"book_type_id"`
+	BookID     *uint      `json:"book_id"`                 // copy set aside once the hold is ready
+	Status     uint       `json:"status" gorm:"default:1"` //1: waiting, 2: ready for pickup, 3: fulfilled, 4: cancelled, 5: expired
+	ReadyAt    *time.Time `json:"ready_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	User       User       `gorm:"foreignKey:UserID"`
+	BookType   BookType   `gorm:"foreignKey:BookTypeID"`
+	CommonTime
+}
+
+type HoldResponse struct {
+	ID        uint       `json:"id"`
+	Title     string     `json:"title"`
+	Status    string     `json:"status"`
+	Position  int        `json:"position"` // place in the queue, 0 once the hold is no longer waiting
+	ReadyAt   *time.Time `json:"ready_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type HoldRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+type HoldSearchRequest struct {
+	Status int `json:"status"` //0: all, 1: active, 2: closed
+	Pagination
+}
+
+func (h *Hold) ToResponse(position int) HoldResponse {
+	var status string
+	switch h.Status {
+	case 1:
+		status = "Waiting"
+	case 2:
+		status = "Ready"
+	case 3:
+		status = "Fulfilled"
+	case 4:
+		status = "Cancelled"
+	default:
+		status = "Expired"
+	}
+
+	return HoldResponse{
+		ID:        h.ID,
+		Title:     h.BookType.Title,
+		Status:    status,
+		Position:  position,
+		ReadyAt:   h.ReadyAt,
+		ExpiresAt: h.ExpiresAt,
+		CreatedAt: h.CreatedAt,
+	}
+}
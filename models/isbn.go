@@ -0,0 +1,63 @@
+package models
+
+import "strings"
+
+// NormalizeISBN strips hyphens and spaces and upper-cases the ISBN-10 check digit
+func NormalizeISBN(isbn string) string {
+	isbn = strings.ToUpper(isbn)
+	return strings.NewReplacer("-", "", " ", "").Replace(isbn)
+}
+
+// ValidISBN10 checks length and the mod 11 checksum of a normalized ISBN-10
+func ValidISBN10(isbn string) bool {
+	if len(isbn) != 10 {
+		return false
+	}
+	sum := 0
+	for i, r := range isbn {
+		var digit int
+		switch {
+		case r >= '0' && r <= '9':
+			digit = int(r - '0')
+		case r == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// ValidISBN13 checks length and the mod 10 checksum of a normalized ISBN-13
+func ValidISBN13(isbn string) bool {
+	if len(isbn) != 13 {
+		return false
+	}
+	sum := 0
+	for i, r := range isbn {
+		if r < '0' || r > '9' {
+			return false
+		}
+		digit := int(r - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// ISBN10To13 converts a valid normalized ISBN-10 to its 978-prefixed ISBN-13
+func ISBN10To13(isbn string) string {
+	isbn13 := "978" + isbn[:9]
+	sum := 0
+	for i, r := range isbn13 {
+		digit := int(r - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return isbn13 + string(rune('0'+(10-sum%10)%10))
+}
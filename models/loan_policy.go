@@ -0,0 +1,57 @@
+package models
+
+// A blank PatronCategory or ItemType matches every category or item type.
+// The most specific policy wins, category before item type.
+type LoanPolicy struct {
+	ID             uint   `json:"id" gorm:"primary_key"`
+	PatronCategory string `json:"patron_category" gorm:"uniqueIndex:idx_loan_policy_scope"`
+	ItemType       string `json:"item_type" gorm:"uniqueIndex:idx_loan_policy_scope"`
+	LoanDays       int    `json:"loan_days"`
+	RenewalDays    int    `json:"renewal_days"`
+	MaxRenewals    int    `json:"max_renewals"`
+	MaxLoans       int    `json:"max_loans"` // concurrent open records, 0 for no limit
+	MaxHolds       int    `json:"max_holds"` // active holds, 0 for no limit
+	CommonTime
+}
+
+// Used when no policy row matches
+var DefaultLoanPolicy = LoanPolicy{
+	LoanDays:    28, // 4 weeks
+	RenewalDays: 21, // 3 weeks
+	MaxRenewals: 3,
+}
+
+type LoanPolicyPayload struct {
+	ID             uint   `json:"id"`
+	PatronCategory string `json:"patron_category"`
+	ItemType       string `json:"item_type"`
+	LoanDays       int    `json:"loan_days" binding:"required,min=1"`
+	RenewalDays    int    `json:"renewal_days" binding:"min=0"`
+	MaxRenewals    int    `json:"max_renewals" binding:"min=0"`
+	MaxLoans       int    `json:"max_loans" binding:"min=0"`
+	MaxHolds       int    `json:"max_holds" binding:"min=0"`
+}
+
+type LoanPolicyRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// Matches reports how specifically the policy applies, -1 when it does not apply
+func (lp *LoanPolicy) Matches(patronCategory, itemType string) int {
+	score := 0
+	switch lp.PatronCategory {
+	case patronCategory:
+		score += 2
+	case "":
+	default:
+		return -1
+	}
+	switch lp.ItemType {
+	case itemType:
+		score += 1
+	case "":
+	default:
+		return -1
+	}
+	return score
+}
@@ -3,35 +3,68 @@ package models
 import "time"
 
 type Record struct {
-	ID         uint
-	UserID     uint
-	BookID     uint
-	ReturnedAt *time.Time
-	DueAt      time.Time
-	User       User `gorm:"foreignKey:UserID"` // Automatically fetch User
-	Book       Book `gorm:"foreignKey:BookID"` // Automatically fetch Book
-	IsClosed   bool `json:"is_closed" gorm:"column:is_closed;default:false"`
+	ID           uint
+	UserID       uint
+	BookID       uint `gorm:"uniqueIndex:idx_records_open_book,where:is_closed = false"` // one open record per copy
+	ReturnedAt   *time.Time
+	DueAt        time.Time
+	RenewalCount int  `json:"renewal_count" gorm:"default:0"`
+	User         User `gorm:"foreignKey:UserID"` // Automatically fetch User
+	Book         Book `gorm:"foreignKey:BookID"` // Automatically fetch Book
+	IsClosed     bool `json:"is_closed" gorm:"column:is_closed;default:false"`
 	CommonTime
 }
 type RecordResponse struct {
-	ID         uint       `json:"id"`
-	Name       string     `json:"name"`
-	Title      string     `json:"title"`
-	ReturnedAt *time.Time `json:"returned_at"`
-	CreatedAt  time.Time  `json:"created_at"`
-	DueAt      time.Time  `json:"due_at"`
-	Status     string     `json:"status"`
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Title        string     `json:"title"`
+	ReturnedAt   *time.Time `json:"returned_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DueAt        time.Time  `json:"due_at"`
+	Status       string     `json:"status"`
+	RenewalCount int        `json:"renewal_count"`
 }
+
+// LoanResponse is a record as the borrow and desk endpoints return it, with
+// the borrower cut down to the fields anyone may see
+type LoanResponse struct {
+	Record
+	User UserResponse
+}
+
 type RecordRequest struct {
 	IDs []uint `json:"ids"`
 }
 
+type CheckOutPayload struct {
+	Barcode string `json:"barcode" binding:"required"`
+	UserID  uint   `json:"user_id" binding:"required"`
+}
+
+type CheckInPayload struct {
+	Barcode string `json:"barcode" binding:"required"`
+}
+
 type RecordSearchRequest struct {
-	Title  string `json:"title"`
-	Status int    `json:"status"` //0: all, 1: open, 2: closed
+	Title  string `json:"title" form:"title"`
+	Status int    `json:"status" form:"status"` //0: all, 1: open, 2: closed
+	Cursor string `json:"cursor" form:"cursor"` // continues from next_cursor of the previous page in place of page
 	Pagination
 }
 
+func (r *Record) ToLoanResponse() LoanResponse {
+	return LoanResponse{Record: *r, User: r.User.ToResponse()}
+}
+
+// ToLoanResponses converts records with ToLoanResponse
+func ToLoanResponses(records []Record) []LoanResponse {
+	loans := make([]LoanResponse, len(records))
+	for i := range records {
+		loans[i] = records[i].ToLoanResponse()
+	}
+	return loans
+}
+
 func (r *Record) ToResponse() (rr RecordResponse) {
 	var status = "Returned"
 	if !r.IsClosed {
@@ -43,13 +76,14 @@ func (r *Record) ToResponse() (rr RecordResponse) {
 	}
 
 	rr = RecordResponse{
-		ID:         r.ID,
-		Name:       r.User.Nickname,
-		Title:      r.Book.BookType.Title,
-		ReturnedAt: r.ReturnedAt,
-		CreatedAt:  r.CreatedAt,
-		DueAt:      r.DueAt,
-		Status:     status,
+		ID:           r.ID,
+		Name:         r.User.Nickname,
+		Title:        r.Book.BookType.Title,
+		ReturnedAt:   r.ReturnedAt,
+		CreatedAt:    r.CreatedAt,
+		DueAt:        r.DueAt,
+		Status:       status,
+		RenewalCount: r.RenewalCount,
 	}
 	return rr
 }
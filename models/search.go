@@ -0,0 +1,41 @@
+package models
+
+type SearchRequest struct {
+	Query     string `json:"q" form:"q" binding:"required"`
+	Language  string `json:"language" form:"language"`
+	YearFrom  int    `json:"year_from" form:"year_from"`
+	YearTo    int    `json:"year_to" form:"year_to"`
+	Available bool   `json:"available" form:"available"` // only titles with a copy on the shelf
+	Pagination
+}
+
+type SearchHit struct {
+	Book       BookResponse      `json:"book"`
+	Score      float64           `json:"score"`      // relevance, only comparable within one response
+	Highlights map[string]string `json:"highlights"` // title, authors, subjects or description with matches in <mark>
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type YearFacet struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+// Each facet counts the matches passing every filter but its own, so the
+// other values of a filtered facet stay visible
+type SearchFacets struct {
+	Languages    []FacetCount `json:"languages"`
+	Years        []YearFacet  `json:"years"`        // by decade
+	Availability []FacetCount `json:"availability"` // "available" and "unavailable"
+}
+
+type SearchResponse struct {
+	Results []SearchHit  `json:"results"`
+	Total   int          `json:"total"`
+	Facets  SearchFacets `json:"facets"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	UserAgent  string     `json:"user_agent"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	User       User       `gorm:"foreignKey:UserID"`
+	CommonTime
+}
+
+// RefreshToken is one link in a session's rotation chain, only the hash is stored
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	SessionID uint       `json:"session_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64"`
+	UsedAt    *time.Time `json:"used_at"` // set once the token has been exchanged
+	Session   Session    `gorm:"foreignKey:SessionID"`
+	CommonTime
+}
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type RefreshPayload struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type SessionRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+type UserSessionRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+func (s *Session) ToResponse(currentID uint) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		Current:    s.ID == currentID,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		CreatedAt:  s.CreatedAt,
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Kinds of soft deleted rows admins can list and restore
+const (
+	TrashUsers   = "users"
+	TrashTitles  = "titles"
+	TrashCopies  = "copies"
+	TrashRecords = "records"
+)
+
+type TrashRequest struct {
+	Type string `json:"type" binding:"required,oneof=users titles copies records"`
+	Pagination
+}
+
+type TrashRestoreRequest struct {
+	Type string `json:"type" binding:"required,oneof=users titles copies records"`
+	IDs  []uint `json:"ids"`
+}
+
+type TrashItem struct {
+	ID        uint      `json:"id"`
+	Label     string    `json:"label"` // username, title, barcode or record summary
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type UserDeleteRequest struct {
+	IDs []uint `json:"ids"`
+}
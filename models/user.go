@@ -1,10 +1,20 @@
 package models
 
+const (
+	RolePatron    = "patron"
+	RoleLibrarian = "librarian"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID       uint   `json:"id" gorm:"primary_key"`
 	Username string `json:"username" gorm:"unique"`
-	Password string `json:"password"`
+	Password string `json:"-"` // bcrypt hash, never sent back
 	Nickname string
+	Email    string `json:"email" gorm:"size:254"`
+	Phone    string `json:"phone" gorm:"size:32"`
+	Role     string `json:"role" gorm:"default:patron"`       // patron, librarian or admin
+	Category string `json:"category" gorm:"default:standard"` // patron category used to pick a loan policy
 	CommonTime
 }
 
@@ -19,7 +29,62 @@ type SignUpPayload struct {
 	Nickname string `json:"nickname" binding:"required"`
 }
 
+type UserRoleRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=patron librarian admin"`
+}
+
+type UserCategoryRequest struct {
+	UserID   uint   `json:"user_id" binding:"required"`
+	Category string `json:"category" binding:"required"`
+}
+
+// ProfileUpdatePayload changes the fields it contains; an empty email or
+// phone clears it
+type ProfileUpdatePayload struct {
+	Nickname *string `json:"nickname" binding:"omitempty,max=64"`
+	Email    *string `json:"email" binding:"omitempty,max=254"`
+	Phone    *string `json:"phone" binding:"omitempty,max=32"`
+}
+
+type PasswordChangePayload struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8,max=72"` // bcrypt reads 72 bytes at most
+}
+
+type AccountDeletePayload struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// ProfileResponse is the signed in user's own account
+type ProfileResponse struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Role     string `json:"role"`
+	Category string `json:"category"`
+}
+
 type UserResponse struct {
 	ID       uint   `json:"id" gorm:"primary_key"`
 	Nickname string `json:"nickname"`
+	Role     string `json:"role"`
+}
+
+func (u *User) ToResponse() UserResponse {
+	return UserResponse{ID: u.ID, Nickname: u.Nickname, Role: u.Role}
+}
+
+func (u *User) ToProfileResponse() ProfileResponse {
+	return ProfileResponse{
+		ID:       u.ID,
+		Username: u.Username,
+		Nickname: u.Nickname,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Role:     u.Role,
+		Category: u.Category,
+	}
 }
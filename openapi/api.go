@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"library/apierror"
+	"library/models"
+	"net/http"
+	"sync"
+)
+
+// message is the body of endpoints that only confirm what they did
+func message() Fields {
+	return Fields{"message": ""}
+}
+
+// messageWith is a confirmation carrying the rows the request created or changed
+func messageWith(data any) Fields {
+	return Fields{"message": "", "data": data}
+}
+
+func tokens() Fields {
+	return Fields{"token": "", "refresh_token": "", "expires_in": 0}
+}
+
+// Routes lists every endpoint routes.RegisterRoutes registers. Keep it in step
+// with the router: TestOpenAPIMatchesRouter fails when one is added without
+// the other.
+var Routes = []Route{
+	{Method: "GET", Path: "/healthz", Tag: "health", Summary: "Liveness probe", Response: Fields{"status": ""}},
+	{Method: "GET", Path: "/readyz", Tag: "health", Summary: "Readiness probe, fails while the database is down or the server is draining", Response: Fields{"status": ""}},
+	{Method: "GET", Path: "/metrics", Tag: "health", Summary: "Prometheus metrics", ContentType: "text/plain; version=0.0.4"},
+	{Method: "GET", Path: "/openapi.json", Tag: "docs", Summary: "This OpenAPI document", Response: Fields{}},
+	{Method: "GET", Path: "/docs", Tag: "docs", Summary: "Interactive API documentation", ContentType: "text/html"},
+
+	{Method: "POST", Path: "/user/signup", Tag: "user", Summary: "Create an account", Request: models.SignUpPayload{}, Status: http.StatusCreated, Response: messageWith(models.ProfileResponse{})},
+	{Method: "POST", Path: "/user/signin", Tag: "user", Summary: "Sign in and start a session", Request: models.SignInPayload{}, Response: tokens()},
+	{Method: "POST", Path: "/user/refresh", Tag: "user", Summary: "Exchange a refresh token for a new token pair", Request: models.RefreshPayload{}, Response: tokens()},
+	{Method: "GET", Path: "/user/info", Tag: "user", Summary: "The signed in user", Access: SignedIn, Response: Fields{"user": models.UserResponse{}}},
+	{Method: "POST", Path: "/user/logout", Tag: "user", Summary: "End the current session", Access: SignedIn, Response: message()},
+	{Method: "POST", Path: "/user/logout-all", Tag: "user", Summary: "End every session of the signed in user", Access: SignedIn, Response: message()},
+	{Method: "POST", Path: "/user/sessions", Tag: "user", Summary: "List active sessions", Access: SignedIn, Response: Fields{"sessions": []models.SessionResponse{}}},
+	{Method: "POST", Path: "/user/sessions/revoke", Tag: "user", Summary: "End some of the signed in user's sessions", Access: SignedIn, Request: models.SessionRequest{}, Response: message()},
+	{Method: "POST", Path: "/user/role", Tag: "user", Summary: "Change a user's role", Access: Admin, Request: models.UserRoleRequest{}, Response: message()},
+	{Method: "POST", Path: "/user/category", Tag: "user", Summary: "Change a user's patron category", Access: Admin, Request: models.UserCategoryRequest{}, Response: message()},
+	{Method: "POST", Path: "/user/revoke-sessions", Tag: "user", Summary: "End every session of a user", Access: Admin, Request: models.UserSessionRequest{}, Response: message()},
+	{Method: "POST", Path: "/user/delete", Tag: "user", Summary: "Delete users without open loans", Access: Admin, Request: models.UserDeleteRequest{}, Response: message()},
+
+	{Method: "POST", Path: "/book/list", Tag: "book", Summary: "Search the catalog", Successor: "GET /api/v1/books", Request: models.BookRequest{}, Response: Fields{"books": []models.BookResponse{}, "total": int64(0), "next_cursor": "", "has_more": false}},
+	{Method: "POST", Path: "/book/borrow", Tag: "book", Summary: "Borrow one copy of each title", Successor: "POST /api/v1/loans", Access: SignedIn, Request: models.BookIDsPayload{}, Response: messageWith([]models.LoanResponse{})},
+
+	{Method: "POST", Path: "/record/list", Tag: "record", Summary: "List the signed in user's loans", Successor: "GET /api/v1/me/loans", Access: SignedIn, Request: models.RecordSearchRequest{}, Response: Fields{"records": []models.RecordResponse{}, "total": int64(0), "next_cursor": "", "has_more": false}},
+	{Method: "POST", Path: "/record/extend", Tag: "record", Summary: "Renew loans", Successor: "PATCH /api/v1/loans/{id}", Access: SignedIn, Request: models.RecordRequest{}, Response: message()},
+	{Method: "POST", Path: "/record/return", Tag: "record", Summary: "Return loans", Successor: "POST /api/v1/loans/{id}/return", Access: SignedIn, Request: models.RecordRequest{}, Response: message()},
+
+	{Method: "GET", Path: "/api/v1/books", Tag: "v1", Summary: "Search the catalog", Query: models.BookRequest{}, Response: Fields{"books": []models.BookResponse{}, "total": int64(0), "next_cursor": "", "has_more": false}},
+	{Method: "GET", Path: "/api/v1/books/:id", Tag: "v1", Summary: "One title with its copies, next due date and hold queue", Response: Fields{"book": models.BookDetailResponse{}}},
+	{Method: "GET", Path: "/api/v1/search", Tag: "v1", Summary: "Ranked full-text search over titles, authors, subjects and descriptions, with facets", Query: models.SearchRequest{}, Response: models.SearchResponse{}},
+	{Method: "POST", Path: "/api/v1/loans", Tag: "v1", Summary: "Borrow one copy of each title", Access: SignedIn, Request: models.BookIDsPayload{}, Response: messageWith([]models.LoanResponse{})},
+	{Method: "PATCH", Path: "/api/v1/loans/:id", Tag: "v1", Summary: "Renew a loan", Access: SignedIn, Response: message()},
+	{Method: "POST", Path: "/api/v1/loans/:id/return", Tag: "v1", Summary: "Return a loan", Access: SignedIn, Response: message()},
+	{Method: "GET", Path: "/api/v1/me/loans", Tag: "v1", Summary: "List the signed in user's loans", Access: SignedIn, Query: models.RecordSearchRequest{}, Response: Fields{"records": []models.RecordResponse{}, "total": int64(0), "next_cursor": "", "has_more": false}},
+	{Method: "GET", Path: "/api/v1/me", Tag: "v1", Summary: "The signed in user's profile", Access: SignedIn, Response: Fields{"user": models.ProfileResponse{}}},
+	{Method: "PATCH", Path: "/api/v1/me", Tag: "v1", Summary: "Update nickname, email and phone", Access: SignedIn, Request: models.ProfileUpdatePayload{}, Response: Fields{"message": "", "user": models.ProfileResponse{}}},
+	{Method: "DELETE", Path: "/api/v1/me", Tag: "v1", Summary: "Delete the signed in user's account, refused while loans are open", Access: SignedIn, Request: models.AccountDeletePayload{}, Response: message()},
+	{Method: "POST", Path: "/api/v1/me/password", Tag: "v1", Summary: "Change password and sign out other sessions", Access: SignedIn, Request: models.PasswordChangePayload{}, Response: message()},
+
+	{Method: "POST", Path: "/hold/place", Tag: "hold", Summary: "Join the queue for titles with no copy available", Access: SignedIn, Request: models.BookIDsPayload{}, Response: messageWith([]models.HoldResponse{})},
+	{Method: "POST", Path: "/hold/list", Tag: "hold", Summary: "List the signed in user's holds", Access: SignedIn, Request: models.HoldSearchRequest{}, Response: Fields{"holds": []models.HoldResponse{}, "total": int64(0)}},
+	{Method: "POST", Path: "/hold/cancel", Tag: "hold", Summary: "Cancel holds", Access: SignedIn, Request: models.HoldRequest{}, Response: message()},
+
+	{Method: "POST", Path: "/fine/list", Tag: "fine", Summary: "List the signed in user's fines and balance", Access: SignedIn, Request: models.FineSearchRequest{}, Response: Fields{"fines": []models.FineResponse{}, "total": int64(0), "balance": int64(0)}},
+	{Method: "POST", Path: "/fine/pay", Tag: "fine", Summary: "Record a payment against a patron's fines", Access: Staff, Request: models.FinePaymentRequest{}, Response: Fields{"message": "", "data": models.FinePayment{}, "balance": int64(0)}},
+	{Method: "POST", Path: "/fine/waive", Tag: "fine", Summary: "Waive fines", Access: Staff, Request: models.FineWaiveRequest{}, Response: message()},
+
+	{Method: "POST", Path: "/catalog/create", Tag: "catalog", Summary: "Add a title and its copies", Access: Staff, Request: models.BookTypePayload{}, Status: http.StatusCreated, Response: messageWith(models.BookType{})},
+	{Method: "POST", Path: "/catalog/update", Tag: "catalog", Summary: "Edit a title", Access: Staff, Request: models.BookTypePayload{}, Response: messageWith(models.BookType{})},
+	{Method: "POST", Path: "/catalog/delete", Tag: "catalog", Summary: "Delete titles with no copy on loan", Access: Staff, Request: models.BookIDsPayload{}, Response: message()},
+	{Method: "POST", Path: "/catalog/copies/add", Tag: "catalog", Summary: "Add copies of a title", Access: Staff, Request: models.BookCopiesPayload{}, Status: http.StatusCreated, Response: messageWith([]models.Book{})},
+	{Method: "POST", Path: "/catalog/copies/withdraw", Tag: "catalog", Summary: "Withdraw a copy from circulation", Access: Staff, Request: models.BookCopyPayload{}, Response: message()},
+	{Method: "POST", Path: "/catalog/copies/move", Tag: "catalog", Summary: "Change where a copy is shelved", Access: Staff, Request: models.BookCopyMovePayload{}, Response: message()},
+
+	{Method: "POST", Path: "/circulation/checkout", Tag: "circulation", Summary: "Lend a copy to a patron at the desk", Access: Staff, Request: models.CheckOutPayload{}, Response: messageWith(models.LoanResponse{})},
+	{Method: "POST", Path: "/circulation/checkin", Tag: "circulation", Summary: "Take a copy back at the desk", Access: Staff, Request: models.CheckInPayload{}, Response: messageWith(models.LoanResponse{})},
+
+	{Method: "POST", Path: "/policy/list", Tag: "policy", Summary: "List loan policies", Access: Staff, Response: Fields{"policies": []models.LoanPolicy{}, "default": models.LoanPolicy{}}},
+	{Method: "POST", Path: "/policy/save", Tag: "policy", Summary: "Create or update a loan policy", Access: Admin, Request: models.LoanPolicyPayload{}, Response: messageWith(models.LoanPolicy{})},
+	{Method: "POST", Path: "/policy/delete", Tag: "policy", Summary: "Delete loan policies", Access: Admin, Request: models.LoanPolicyRequest{}, Response: message()},
+
+	{Method: "POST", Path: "/trash/list", Tag: "trash", Summary: "List soft deleted rows", Access: Admin, Request: models.TrashRequest{}, Response: Fields{"items": []models.TrashItem{}, "total": int64(0)}},
+	{Method: "POST", Path: "/trash/restore", Tag: "trash", Summary: "Restore soft deleted rows", Access: Admin, Request: models.TrashRestoreRequest{}, Response: message()},
+}
+
+var info = Info{
+	Title:   "e-library API",
+	Version: "1.0.0",
+	Description: "Errors share one envelope; branch on error.code, which is stable. " +
+		"Authenticated routes take the access token from /user/signin as a bearer token.",
+}
+
+// Spec is the document for Routes, built on first use
+var Spec = sync.OnceValue(func() *Document {
+	return Build(info, Routes, Fields{"error": apierror.Error{}})
+})
@@ -0,0 +1,196 @@
+// Package openapi describes the HTTP API as an OpenAPI 3 document. The routes
+// are listed in api.go next to the request and response types they take;
+// schemas are generated from those types so they follow the models package.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Who may call a route
+const (
+	Public   = ""
+	SignedIn = "signed_in"
+	Staff    = "staff"
+	Admin    = "admin"
+)
+
+// Route documents one endpoint registered on the router
+type Route struct {
+	Method      string
+	Path        string // gin syntax, e.g. /books/:id
+	Tag         string
+	Summary     string
+	Access      string
+	Query       any    // struct whose form tagged fields are the query parameters
+	Request     any    // sample of the JSON body, nil when there is none
+	Status      int    // success status, 200 when zero
+	Response    any    // sample of the success body
+	ContentType string // success content type when it is not JSON
+	Successor   string // route replacing this one, which marks it deprecated
+}
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary"`
+	OperationID string                `json:"operationId"`
+	Description string                `json:"description,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+const bearerAuth = "bearerAuth"
+
+var accessDescriptions = map[string]string{
+	SignedIn: "Requires a signed in user.",
+	Staff:    "Requires the librarian or admin role.",
+	Admin:    "Requires the admin role.",
+}
+
+// Build generates the document for routes
+func Build(info Info, routes []Route, errorBody any) *Document {
+	s := newSchemas()
+	errorSchema := s.of(errorBody)
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		path, params := openAPIPath(route.Path)
+		if route.Query != nil {
+			params = append(params, s.queryParameters(reflect.TypeOf(route.Query))...)
+		}
+		op := &Operation{
+			Summary:     route.Summary,
+			OperationID: operationID(route.Method, route.Path),
+			Description: accessDescriptions[route.Access],
+			Parameters:  params,
+			Responses:   map[string]Response{},
+		}
+		if route.Successor != "" {
+			op.Deprecated = true
+			op.Description = strings.TrimSpace("Deprecated, use " + route.Successor + ". " + op.Description)
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+		if route.Access != Public {
+			op.Security = []map[string][]string{{bearerAuth: {}}}
+		}
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: s.of(route.Request)}},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := Response{Description: http.StatusText(status)}
+		switch {
+		case route.ContentType != "":
+			success.Content = map[string]MediaType{route.ContentType: {Schema: &Schema{Type: "string"}}}
+		case route.Response != nil:
+			success.Content = map[string]MediaType{"application/json": {Schema: s.of(route.Response)}}
+		}
+		op.Responses[strconv.Itoa(status)] = success
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content:     map[string]MediaType{"application/json": {Schema: errorSchema}},
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+	return doc
+}
+
+// openAPIPath turns gin's :name segments into {name} and their parameters
+func openAPIPath(path string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "integer", Minimum: float(1)},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID derives a stable ID such as post_book_borrow
+func operationID(method, path string) string {
+	replacer := strings.NewReplacer("/", "_", "-", "_", ":", "", ".", "_")
+	return strings.ToLower(method) + replacer.Replace(strings.TrimSuffix(path, "/"))
+}
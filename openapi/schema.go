@@ -0,0 +1,227 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the generator emits
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Fields describes a JSON object built inline by a handler, e.g. a gin.H
+// wrapping a list and its total. Values are sample Go values of each field.
+type Fields map[string]any
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	fieldsType    = reflect.TypeOf(Fields{})
+)
+
+// schemas turns Go types into schemas, putting named structs under
+// components so each is described once and referenced everywhere else
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// of describes the JSON encoding/json produces for value
+func (s *schemas) of(value any) *Schema {
+	if fields, ok := value.(Fields); ok {
+		return s.fields(fields)
+	}
+	return s.typeOf(reflect.TypeOf(value))
+}
+
+func (s *schemas) fields(fields Fields) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for name, value := range fields {
+		schema.Properties[name] = s.of(value)
+		schema.Required = append(schema.Required, name)
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (s *schemas) typeOf(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case fieldsType:
+		return &Schema{Type: "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.typeOf(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.typeOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.typeOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.component(t)
+	}
+	return &Schema{}
+}
+
+// component registers a named struct once and returns a reference to it
+func (s *schemas) component(t reflect.Type) *Schema {
+	name, ok := s.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := s.components[name]; taken {
+			name = strings.ReplaceAll(t.PkgPath(), "/", "_") + "_" + name
+		}
+		s.names[t] = name
+		// Reserve the name before descending so self references terminate
+		s.components[name] = &Schema{}
+		*s.components[name] = *s.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(schema, t)
+	return schema
+}
+
+// addFields follows encoding/json: json tags name fields, "-" hides them and
+// untagged embedded structs are flattened into the parent
+func (s *schemas) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(schema, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.typeOf(field.Type)
+		if applyBinding(property, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// queryParameters describes the fields of t gin binds from the query string,
+// named by their form tags
+func (s *schemas) queryParameters(t reflect.Type) []Parameter {
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			params = append(params, s.queryParameters(field.Type)...)
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := s.typeOf(field.Type)
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: applyBinding(schema, field.Tag.Get("binding")),
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+// applyBinding copies the validator rules gin enforces into the schema and
+// reports whether the field is required
+func applyBinding(schema *Schema, binding string) (required bool) {
+	if binding == "" {
+		return false
+	}
+	numeric := schema.Type == "integer" || schema.Type == "number"
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+		case "oneof":
+			schema.Enum = strings.Fields(value)
+		case "min", "max", "gt", "len":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			switch {
+			case numeric && key == "min":
+				schema.Minimum = float(n)
+			case numeric && key == "gt":
+				schema.Minimum, schema.ExclusiveMinimum = float(n), true
+			case numeric && key == "max":
+				schema.Maximum = float(n)
+			case schema.Type == "string" && key == "len":
+				schema.MinLength, schema.MaxLength = &n, &n
+			}
+		}
+	}
+	return required
+}
+
+func float(n int) *float64 {
+	f := float64(n)
+	return &f
+}
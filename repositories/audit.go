@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"library/models"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	Record(entry *models.AuditEntry) error
+}
+
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAuditRepository) Record(entry *models.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
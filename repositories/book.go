@@ -0,0 +1,427 @@
+package repositories
+
+import (
+	"library/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type BookRepository interface {
+	// Search lists a page of titles matching the request, in the requested
+	// order, with their authors and subjects. A cursor from another sort order
+	// is ErrInvalidCursor.
+	Search(request models.BookRequest) ([]models.BookType, models.PageInfo, error)
+	// CopyCounts counts copies in the collection and on the shelf per title
+	CopyCounts(bookTypeIDs []uint) (total, available map[uint]int, err error)
+	FindBookType(id uint) (models.BookType, error)
+	// FindBookTypeDetails loads one title with its authors and subjects
+	FindBookTypeDetails(id uint) (models.BookType, error)
+	FindBookTypesDetails(ids []uint) ([]models.BookType, error)
+	// Copies lists a title's copies in the collection, withdrawn ones left out
+	Copies(bookTypeID uint) ([]models.Book, error)
+	// CreateBookType inserts a title along with its links to authors and subjects
+	CreateBookType(bookType *models.BookType) error
+	// UpdateBookType saves a title's metadata and replaces its authors and subjects
+	UpdateBookType(bookType models.BookType) error
+	// DeleteBookTypes soft deletes the titles and withdraws their copies not
+	// on loan with the same deletion time, so a restore brings them back
+	// together. It reports how many copies it withdrew.
+	DeleteBookTypes(ids []uint, deletedAt time.Time) (int64, error)
+	// FindOrCreateAuthor and FindOrCreateSubject look a name or heading up,
+	// creating it the first time it is seen
+	FindOrCreateAuthor(name string) (models.Author, error)
+	FindOrCreateSubject(heading string) (models.Subject, error)
+
+	FindCopy(id uint) (models.Book, error)
+	FindCopyByBarcode(barcode string) (models.Book, error)
+	FindCopies(ids []uint) ([]models.Book, error)
+	CountCopiesWithStatus(bookTypeIDs []uint, status uint) (int64, error)
+	CountCopies(bookTypeIDs []uint) (int64, error)
+	// BarcodesTaken counts the barcodes already given to a copy
+	BarcodesTaken(barcodes []string) (int64, error)
+	// CreateCopies inserts the copies, giving those without a barcode one
+	// derived from their ID
+	CreateCopies(books []models.Book) error
+	FirstCopyWithStatus(bookTypeID, status uint) (models.Book, error)
+	// ClaimCopy rents out a copy only if it still has fromStatus, and reports whether it did
+	ClaimCopy(id, fromStatus uint) (bool, error)
+	// SetCopyStatus moves a copy to status only if it still has fromStatus, and
+	// reports whether it did
+	SetCopyStatus(id, fromStatus, status uint) (bool, error)
+	// WithdrawCopy withdraws a copy only if it is still on the shelf, and reports whether it did
+	WithdrawCopy(id uint, deletedAt time.Time) (bool, error)
+	// MoveCopy reports whether the copy exists
+	MoveCopy(id uint, shelfLocation string) (bool, error)
+}
+
+type gormBookRepository struct {
+	db *gorm.DB
+}
+
+// bookKey is a listed title's id and the value the list is sorted on
+type bookKey struct {
+	ID       uint
+	SortText string
+	SortTime time.Time
+	SortN    int64
+}
+
+func (r *gormBookRepository) Search(request models.BookRequest) ([]models.BookType, models.PageInfo, error) {
+	sortOrder := request.Sort
+	if sortOrder == "" {
+		sortOrder = "id"
+	}
+	var after *cursor
+	if request.Cursor != "" {
+		c, err := decodeCursor(request.Cursor, sortOrder)
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		after = &c
+	}
+
+	query := r.db.Model(&models.BookType{})
+	if request.Title != "" {
+		query = query.Where(containsFold("title", request.Title))
+	}
+	if request.Author != "" {
+		query = query.Where("id IN (?)", r.db.Table("book_type_authors").
+			Select("book_type_authors.book_type_id").
+			Joins("JOIN authors ON authors.id = book_type_authors.author_id").
+			Where(containsFold("authors.name", request.Author)))
+	}
+	if request.ISBN != "" {
+		isbn := models.NormalizeISBN(request.ISBN)
+		query = query.Where("isbn10 = ? OR isbn13 = ?", isbn, isbn)
+	}
+	if request.Publisher != "" {
+		query = query.Where(containsFold("publisher", request.Publisher))
+	}
+	if request.Language != "" {
+		query = query.Where("language = ?", strings.ToLower(request.Language))
+	}
+	if request.Subject != "" {
+		query = query.Where("id IN (?)", r.db.Table("book_type_subjects").
+			Select("book_type_subjects.book_type_id").
+			Joins("JOIN subjects ON subjects.id = book_type_subjects.subject_id").
+			Where(containsFold("subjects.heading", request.Subject)))
+	}
+	if request.YearFrom != 0 {
+		query = query.Where("publication_year >= ?", request.YearFrom)
+	}
+	if request.YearTo != 0 {
+		query = query.Where("publication_year <= ?", request.YearTo)
+	}
+	if request.Available {
+		query = query.Where("id IN (?)", r.db.Model(&models.Book{}).Select("book_type_id").Where("status = 1"))
+	}
+	// Count and page share the filters
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	// key is what the order sorts on before the id, selected as sort_text,
+	// sort_time or sort_n so the cursor can carry it
+	var key, column string
+	keyDesc, idDesc := false, false
+	page := query
+	switch sortOrder {
+	case "title":
+		key, column = "LOWER(book_types.title)", "sort_text"
+	case "newest":
+		key, column = "book_types.created_at", "sort_time"
+		keyDesc, idDesc = true, true
+	case "popular":
+		borrows := r.db.Model(&models.Record{}).
+			Select("books.book_type_id, COUNT(*) AS borrow_count").
+			Joins("JOIN books ON books.id = records.book_id").
+			Group("books.book_type_id")
+		page = page.Joins("LEFT JOIN (?) AS borrows ON borrows.book_type_id = book_types.id", borrows)
+		key, column, keyDesc = "COALESCE(borrows.borrow_count, 0)", "sort_n", true
+	case "available":
+		shelf := r.db.Model(&models.Book{}).
+			Select("book_type_id, COUNT(*) AS available_count").
+			Where("status = 1").
+			Group("book_type_id")
+		page = page.Joins("LEFT JOIN (?) AS shelf ON shelf.book_type_id = book_types.id", shelf)
+		key, column, keyDesc = "COALESCE(shelf.available_count, 0)", "sort_n", true
+	}
+
+	idOrder, idAfter := "book_types.id", "book_types.id > ?"
+	if idDesc {
+		idOrder, idAfter = "book_types.id DESC", "book_types.id < ?"
+	}
+	if key == "" {
+		page = page.Select("book_types.id").Order(idOrder)
+		if after != nil {
+			page = page.Where(idAfter, after.ID)
+		}
+	} else {
+		keyOrder, keyAfter := key, key+" > ?"
+		if keyDesc {
+			keyOrder, keyAfter = key+" DESC", key+" < ?"
+		}
+		page = page.Select("book_types.id, " + key + " AS " + column).Order(keyOrder).Order(idOrder)
+		if after != nil {
+			var value any
+			switch column {
+			case "sort_text":
+				value = after.Text
+			case "sort_time":
+				value, _ = time.Parse(time.RFC3339Nano, after.Time)
+			case "sort_n":
+				value = after.N
+			}
+			page = page.Where("("+keyAfter+" OR ("+key+" = ? AND "+idAfter+"))", value, value, after.ID)
+		}
+	}
+	if after == nil {
+		page = page.Offset(request.Offset())
+	}
+
+	var rows []bookKey
+	limit := request.Limit()
+	if err := page.Limit(limit + 1).Scan(&rows).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	rows, info := pageInfo(rows, limit, total, func(row bookKey) cursor {
+		c := cursor{Sort: sortOrder, ID: row.ID}
+		switch column {
+		case "sort_text":
+			c.Text = row.SortText
+		case "sort_time":
+			c.Time = row.SortTime.Format(time.RFC3339Nano)
+		case "sort_n":
+			c.N = row.SortN
+		}
+		return c
+	})
+	if len(rows) == 0 {
+		return nil, info, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	details, err := r.FindBookTypesDetails(ids)
+	if err != nil {
+		return nil, info, err
+	}
+	byID := make(map[uint]models.BookType, len(details))
+	for _, bookType := range details {
+		byID[bookType.ID] = bookType
+	}
+	bookTypes := make([]models.BookType, 0, len(ids))
+	for _, id := range ids {
+		if bookType, ok := byID[id]; ok {
+			bookTypes = append(bookTypes, bookType)
+		}
+	}
+	return bookTypes, info, nil
+}
+
+func (r *gormBookRepository) CopyCounts(bookTypeIDs []uint) (map[uint]int, map[uint]int, error) {
+	var counts []struct {
+		BookTypeID uint
+		Status     uint
+		Count      int
+	}
+	if err := r.db.Model(&models.Book{}).
+		Select("book_type_id, status, COUNT(*) as count").
+		Where("book_type_id IN ?", bookTypeIDs).
+		Group("book_type_id, status").
+		Scan(&counts).Error; err != nil {
+		return nil, nil, err
+	}
+
+	total := make(map[uint]int)
+	available := make(map[uint]int)
+	for _, count := range counts {
+		if count.Status != 4 {
+			total[count.BookTypeID] += count.Count
+		}
+		if count.Status == 1 {
+			available[count.BookTypeID] += count.Count
+		}
+	}
+	return total, available, nil
+}
+
+func (r *gormBookRepository) FindBookType(id uint) (models.BookType, error) {
+	var bookType models.BookType
+	err := r.db.Where("id = ?", id).First(&bookType).Error
+	return bookType, err
+}
+
+func (r *gormBookRepository) FindBookTypeDetails(id uint) (models.BookType, error) {
+	var bookType models.BookType
+	err := r.db.Preload("Authors").Preload("Subjects").Where("id = ?", id).First(&bookType).Error
+	return bookType, err
+}
+
+func (r *gormBookRepository) FindBookTypesDetails(ids []uint) ([]models.BookType, error) {
+	var bookTypes []models.BookType
+	err := r.db.Preload("Authors").Preload("Subjects").Where("id IN ?", ids).Find(&bookTypes).Error
+	return bookTypes, err
+}
+
+func (r *gormBookRepository) Copies(bookTypeID uint) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("book_type_id = ? AND status <> 4", bookTypeID).Order("id").Find(&books).Error
+	return books, err
+}
+
+func (r *gormBookRepository) CreateBookType(bookType *models.BookType) error {
+	return r.db.Create(bookType).Error
+}
+
+func (r *gormBookRepository) UpdateBookType(bookType models.BookType) error {
+	if err := r.db.Model(&models.BookType{}).Where("id = ?", bookType.ID).Updates(map[string]interface{}{
+		"title":            bookType.Title,
+		"isbn10":           bookType.ISBN10,
+		"isbn13":           bookType.ISBN13,
+		"publisher":        bookType.Publisher,
+		"publication_year": bookType.PublicationYear,
+		"language":         bookType.Language,
+		"page_count":       bookType.PageCount,
+		"description":      bookType.Description,
+		"item_type":        bookType.ItemType,
+	}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Model(&bookType).Association("Authors").Replace(bookType.Authors); err != nil {
+		return err
+	}
+	return r.db.Model(&bookType).Association("Subjects").Replace(bookType.Subjects)
+}
+
+func (r *gormBookRepository) DeleteBookTypes(ids []uint, deletedAt time.Time) (int64, error) {
+	if err := r.db.Model(&models.BookType{}).
+		Where("id IN ?", ids).
+		Update("deleted_at", deletedAt).Error; err != nil {
+		return 0, err
+	}
+	result := r.db.Model(&models.Book{}).
+		Where("book_type_id IN ? AND status <> 2", ids).
+		Updates(map[string]interface{}{"status": 4, "deleted_at": deletedAt})
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormBookRepository) FindOrCreateAuthor(name string) (models.Author, error) {
+	var author models.Author
+	err := r.db.Where(models.Author{Name: name}).FirstOrCreate(&author).Error
+	return author, err
+}
+
+func (r *gormBookRepository) FindOrCreateSubject(heading string) (models.Subject, error) {
+	var subject models.Subject
+	err := r.db.Where(models.Subject{Heading: heading}).FirstOrCreate(&subject).Error
+	return subject, err
+}
+
+func (r *gormBookRepository) FindCopy(id uint) (models.Book, error) {
+	var book models.Book
+	err := r.db.Where("id = ?", id).First(&book).Error
+	return book, err
+}
+
+func (r *gormBookRepository) FindCopyByBarcode(barcode string) (models.Book, error) {
+	var book models.Book
+	err := r.db.Where("barcode = ?", barcode).First(&book).Error
+	return book, err
+}
+
+func (r *gormBookRepository) FindCopies(ids []uint) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("id IN ?", ids).Order("id").Find(&books).Error
+	return books, err
+}
+
+func (r *gormBookRepository) CountCopiesWithStatus(bookTypeIDs []uint, status uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Book{}).
+		Where("book_type_id IN ? AND status = ?", bookTypeIDs, status).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormBookRepository) CountCopies(bookTypeIDs []uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Book{}).Where("book_type_id IN ?", bookTypeIDs).Count(&count).Error
+	return count, err
+}
+
+func (r *gormBookRepository) BarcodesTaken(barcodes []string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Book{}).Where("barcode IN ?", barcodes).Count(&count).Error
+	return count, err
+}
+
+func (r *gormBookRepository) CreateCopies(books []models.Book) error {
+	if err := r.db.Omit("BookType").Create(&books).Error; err != nil {
+		return err
+	}
+	for i, book := range books {
+		if book.Barcode != nil {
+			continue
+		}
+		barcode := models.DefaultBarcode(book.ID)
+		if err := r.db.Model(&models.Book{}).Where("id = ?", book.ID).Update("barcode", barcode).Error; err != nil {
+			return err
+		}
+		books[i].Barcode = &barcode
+	}
+	return nil
+}
+
+func (r *gormBookRepository) FirstCopyWithStatus(bookTypeID, status uint) (models.Book, error) {
+	var book models.Book
+	err := r.db.Where("book_type_id = ? AND status = ?", bookTypeID, status).
+		Order("id").
+		First(&book).Error
+	return book, err
+}
+
+func (r *gormBookRepository) ClaimCopy(id, fromStatus uint) (bool, error) {
+	result := r.db.Model(&models.Book{}).
+		Where("id = ? AND status = ?", id, fromStatus).
+		Update("status", 2)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormBookRepository) SetCopyStatus(id, fromStatus, status uint) (bool, error) {
+	result := r.db.Model(&models.Book{}).
+		Where("id = ? AND status = ?", id, fromStatus).
+		Update("status", status)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormBookRepository) WithdrawCopy(id uint, deletedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Book{}).
+		Where("id = ? AND status = 1", id).
+		Updates(map[string]interface{}{"status": 4, "deleted_at": deletedAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormBookRepository) MoveCopy(id uint, shelfLocation string) (bool, error) {
+	result := r.db.Model(&models.Book{}).Where("id = ?", id).Update("shelf_location", shelfLocation)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,333 @@
+package repositories
+
+import (
+	"library/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type HoldRepository interface {
+	// List pages through a user's holds with the given statuses, all when
+	// statuses is empty, with their titles, deleted ones included
+	List(userID uint, statuses []uint, page models.Pagination) ([]models.Hold, int64, error)
+	FindByIDs(ids []uint) ([]models.Hold, error)
+	// QueuePosition is the 1-based place of a waiting hold in its title's
+	// queue, 0 for holds no longer waiting
+	QueuePosition(hold models.Hold) (int, error)
+	// ReadyFor finds the user's hold on a title that has a copy set aside
+	ReadyFor(userID, bookTypeID uint) (models.Hold, error)
+	CountReadyForCopy(userID, bookID uint) (int64, error)
+	// FirstWaiting finds the hold at the front of a title's queue
+	FirstWaiting(bookTypeID uint) (models.Hold, error)
+	// CountWaiting is the length of a title's queue
+	CountWaiting(bookTypeID uint) (int64, error)
+	ExpiredReady(now time.Time) ([]models.Hold, error)
+	Active(userID uint) ([]models.Hold, error)
+	// MarkReady sets a copy aside for a hold only if it is still waiting, and
+	// reports whether it did
+	MarkReady(id, bookID uint, readyAt, expiresAt time.Time) (bool, error)
+	Create(holds []models.Hold) error
+	// Expire closes a ready hold only if it is still ready, and reports whether it did
+	Expire(id uint) (bool, error)
+	// Cancel cancels a hold only if it still has fromStatus, and reports whether it did
+	Cancel(id, fromStatus uint) (bool, error)
+	// Fulfil closes the user's active holds on the titles
+	Fulfil(userID uint, bookTypeIDs []uint) error
+	// CancelForTitles cancels every active hold on the titles
+	CancelForTitles(bookTypeIDs []uint) error
+}
+
+type FineRepository interface {
+	// List pages through a user's fines with the given statuses, all when
+	// statuses is empty, with the records and titles they were charged for
+	List(userID uint, statuses []uint, page models.Pagination) ([]models.Fine, int64, error)
+	FindByIDs(ids []uint) ([]models.Fine, error)
+	// Outstanding lists the user's unsettled fines, oldest first
+	Outstanding(userID uint) ([]models.Fine, error)
+	OutstandingBalance(userID uint) (int64, error)
+	Create(fine *models.Fine) error
+	// ApplyPayment settles amount of an outstanding fine, marking it paid once
+	// nothing is left. It reports false, changing nothing, when the fine is no
+	// longer outstanding or owes less than amount.
+	ApplyPayment(id uint, amount int64) (bool, error)
+	// Waive writes off what is left of a fine, and reports false when it was
+	// no longer outstanding
+	Waive(id, staffID uint, note string) (bool, error)
+	CreatePayment(payment *models.FinePayment) error
+}
+
+type PolicyRepository interface {
+	// List orders the policies by patron category and item type
+	List() ([]models.LoanPolicy, error)
+	// Candidates lists the policies that apply to the category and item type, wildcards included
+	Candidates(patronCategory, itemType string) ([]models.LoanPolicy, error)
+	// ScopeTaken reports whether a policy other than exceptID covers the category and item type
+	ScopeTaken(patronCategory, itemType string, exceptID uint) (bool, error)
+	Create(policy *models.LoanPolicy) error
+	// Update reports whether the policy exists
+	Update(policy models.LoanPolicy) (bool, error)
+	Delete(ids []uint) error
+}
+
+type gormHoldRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormHoldRepository) List(userID uint, statuses []uint, page models.Pagination) ([]models.Hold, int64, error) {
+	query := r.db.Model(&models.Hold{}).Where("user_id = ?", userID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	// Count and page share the filters
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var holds []models.Hold
+	err := query.
+		Preload("BookType", withDeleted).
+		Order("id").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&holds).Error
+	return holds, total, err
+}
+
+func (r *gormHoldRepository) FindByIDs(ids []uint) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.Where("id IN ?", ids).Find(&holds).Error
+	return holds, err
+}
+
+func (r *gormHoldRepository) QueuePosition(hold models.Hold) (int, error) {
+	if hold.Status != 1 {
+		return 0, nil
+	}
+	var ahead int64
+	if err := r.db.Model(&models.Hold{}).
+		Where("book_type_id = ? AND status = 1 AND id < ?", hold.BookTypeID, hold.ID).
+		Count(&ahead).Error; err != nil {
+		return 0, err
+	}
+	return int(ahead) + 1, nil
+}
+
+func (r *gormHoldRepository) ReadyFor(userID, bookTypeID uint) (models.Hold, error) {
+	var hold models.Hold
+	err := r.db.Where("user_id = ? AND book_type_id = ? AND status = 2", userID, bookTypeID).First(&hold).Error
+	return hold, err
+}
+
+func (r *gormHoldRepository) CountReadyForCopy(userID, bookID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Hold{}).
+		Where("user_id = ? AND book_id = ? AND status = 2", userID, bookID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormHoldRepository) FirstWaiting(bookTypeID uint) (models.Hold, error) {
+	var hold models.Hold
+	err := r.db.Where("book_type_id = ? AND status = 1", bookTypeID).Order("id").First(&hold).Error
+	return hold, err
+}
+
+func (r *gormHoldRepository) CountWaiting(bookTypeID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Hold{}).Where("book_type_id = ? AND status = 1", bookTypeID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormHoldRepository) ExpiredReady(now time.Time) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.Where("status = 2 AND expires_at < ?", now).Find(&holds).Error
+	return holds, err
+}
+
+func (r *gormHoldRepository) Active(userID uint) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.Where("user_id = ? AND status IN ?", userID, []uint{1, 2}).Find(&holds).Error
+	return holds, err
+}
+
+func (r *gormHoldRepository) MarkReady(id, bookID uint, readyAt, expiresAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Hold{}).
+		Where("id = ? AND status = 1", id).
+		Updates(map[string]interface{}{
+			"status":     2,
+			"book_id":    bookID,
+			"ready_at":   readyAt,
+			"expires_at": expiresAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormHoldRepository) Create(holds []models.Hold) error {
+	return r.db.Omit("User", "BookType").Create(&holds).Error
+}
+
+func (r *gormHoldRepository) Expire(id uint) (bool, error) {
+	result := r.db.Model(&models.Hold{}).
+		Where("id = ? AND status = 2", id).
+		Update("status", 5)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormHoldRepository) Cancel(id, fromStatus uint) (bool, error) {
+	result := r.db.Model(&models.Hold{}).
+		Where("id = ? AND status = ?", id, fromStatus).
+		Update("status", 4)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormHoldRepository) Fulfil(userID uint, bookTypeIDs []uint) error {
+	return r.db.Model(&models.Hold{}).
+		Where("user_id = ? AND book_type_id IN ? AND status IN ?", userID, bookTypeIDs, []uint{1, 2}).
+		Update("status", 3).Error
+}
+
+func (r *gormHoldRepository) CancelForTitles(bookTypeIDs []uint) error {
+	return r.db.Model(&models.Hold{}).
+		Where("book_type_id IN ? AND status IN ?", bookTypeIDs, []uint{1, 2}).
+		Update("status", 4).Error
+}
+
+type gormFineRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormFineRepository) List(userID uint, statuses []uint, page models.Pagination) ([]models.Fine, int64, error) {
+	query := r.db.Model(&models.Fine{}).Where("user_id = ?", userID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	// Count and page share the filters
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var fines []models.Fine
+	err := query.
+		Preload("Record", withDeleted).
+		Preload("Record.Book", withDeleted).
+		Preload("Record.Book.BookType", withDeleted).
+		Order("id").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&fines).Error
+	return fines, total, err
+}
+
+func (r *gormFineRepository) FindByIDs(ids []uint) ([]models.Fine, error) {
+	var fines []models.Fine
+	err := r.db.Where("id IN ?", ids).Find(&fines).Error
+	return fines, err
+}
+
+func (r *gormFineRepository) Outstanding(userID uint) ([]models.Fine, error) {
+	var fines []models.Fine
+	err := r.db.Where("user_id = ? AND status = 1", userID).Order("id").Find(&fines).Error
+	return fines, err
+}
+
+func (r *gormFineRepository) OutstandingBalance(userID uint) (int64, error) {
+	var balance int64
+	err := r.db.Model(&models.Fine{}).
+		Select("COALESCE(SUM(amount - paid - waived), 0)").
+		Where("user_id = ? AND status = 1", userID).
+		Scan(&balance).Error
+	return balance, err
+}
+
+func (r *gormFineRepository) Create(fine *models.Fine) error {
+	return r.db.Omit("User", "Record").Create(fine).Error
+}
+
+func (r *gormFineRepository) ApplyPayment(id uint, amount int64) (bool, error) {
+	// Built on the stored amounts, so a concurrent payment or waiver is
+	// never overwritten
+	result := r.db.Model(&models.Fine{}).
+		Where("id = ? AND status = 1 AND paid + waived + ? <= amount", id, amount).
+		Updates(map[string]interface{}{
+			"paid":   gorm.Expr("paid + ?", amount),
+			"status": gorm.Expr("CASE WHEN paid + waived + ? = amount THEN 2 ELSE 1 END", amount),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormFineRepository) Waive(id, staffID uint, note string) (bool, error) {
+	result := r.db.Model(&models.Fine{}).
+		Where("id = ? AND status = 1", id).
+		Updates(map[string]interface{}{
+			"waived":       gorm.Expr("amount - paid"),
+			"status":       3,
+			"waived_by_id": staffID,
+			"note":         note,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormFineRepository) CreatePayment(payment *models.FinePayment) error {
+	return r.db.Create(payment).Error
+}
+
+type gormPolicyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormPolicyRepository) List() ([]models.LoanPolicy, error) {
+	var policies []models.LoanPolicy
+	err := r.db.Order("patron_category, item_type").Find(&policies).Error
+	return policies, err
+}
+
+func (r *gormPolicyRepository) Candidates(patronCategory, itemType string) ([]models.LoanPolicy, error) {
+	var policies []models.LoanPolicy
+	err := r.db.Where("patron_category IN ? AND item_type IN ?", []string{patronCategory, ""}, []string{itemType, ""}).
+		Find(&policies).Error
+	return policies, err
+}
+
+func (r *gormPolicyRepository) ScopeTaken(patronCategory, itemType string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.LoanPolicy{}).
+		Where("patron_category = ? AND item_type = ? AND id <> ?", patronCategory, itemType, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormPolicyRepository) Create(policy *models.LoanPolicy) error {
+	return r.db.Create(policy).Error
+}
+
+func (r *gormPolicyRepository) Update(policy models.LoanPolicy) (bool, error) {
+	result := r.db.Model(&models.LoanPolicy{}).
+		Where("id = ?", policy.ID).
+		Select("patron_category", "item_type", "loan_days", "renewal_days", "max_renewals", "max_loans", "max_holds").
+		Updates(&policy)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormPolicyRepository) Delete(ids []uint) error {
+	return r.db.Where("id IN ?", ids).Delete(&models.LoanPolicy{}).Error
+}
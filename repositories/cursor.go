@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"library/models"
+	"time"
+)
+
+// cursor is the last row of a page: its id and, for lists not ordered by id
+// alone, the value it was sorted on. Clients get it base64 encoded and must
+// treat it as opaque.
+type cursor struct {
+	Sort string `json:"s"`
+	ID   uint   `json:"id"`
+	Text string `json:"t,omitempty"`
+	Time string `json:"at,omitempty"`
+	N    int64  `json:"n,omitempty"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor issued for the sort order
+func decodeCursor(encoded, sort string) (cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort || c.ID == 0 {
+		return cursor{}, ErrInvalidCursor
+	}
+	if c.Time != "" {
+		if _, err := time.Parse(time.RFC3339Nano, c.Time); err != nil {
+			return cursor{}, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// pageInfo trims the extra row a page query fetches to learn whether more
+// follow, and returns the cursor of the last row kept
+func pageInfo[T any](rows []T, limit int, total int64, last func(T) cursor) ([]T, models.PageInfo) {
+	info := models.PageInfo{Total: total}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		info.HasMore = true
+		info.NextCursor = last(rows[limit-1]).encode()
+	}
+	return rows, info
+}
@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"library/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RecordRepository interface {
+	// List pages through a user's records by id with their copies and titles,
+	// deleted titles included. A malformed cursor is ErrInvalidCursor.
+	List(userID uint, request models.RecordSearchRequest) ([]models.Record, models.PageInfo, error)
+	// FindByIDs loads the records with their copies
+	FindByIDs(ids []uint) ([]models.Record, error)
+	FindOpenByCopy(bookID uint) (models.Record, error)
+	// OpenDueDates maps each of the copies that is on loan to its due date
+	OpenDueDates(bookIDs []uint) (map[uint]time.Time, error)
+	CountOpen(userIDs ...uint) (int64, error)
+	// CountLoans counts every open record and those of them past due at now
+	CountLoans(now time.Time) (active, overdue int64, err error)
+	// Create inserts the records, ErrDuplicate means a copy already has an open record
+	Create(records []models.Record) error
+	// Close marks the open records among ids returned and loads the ones it
+	// closed. Records closed by someone else in the meantime are left out.
+	Close(ids []uint, returnedAt time.Time) ([]models.Record, error)
+	// Renew moves the due date of a record that is still open and still has
+	// renewalCount renewals, and reports whether it did
+	Renew(id uint, renewalCount int, dueAt time.Time) (bool, error)
+	DeleteClosed(userID uint, deletedAt time.Time) error
+}
+
+type gormRecordRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormRecordRepository) List(userID uint, request models.RecordSearchRequest) ([]models.Record, models.PageInfo, error) {
+	var after *cursor
+	if request.Cursor != "" {
+		c, err := decodeCursor(request.Cursor, "id")
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		after = &c
+	}
+
+	query := r.db.Model(&models.Record{}).Where("records.user_id = ?", userID)
+	if request.Status != 0 {
+		query = query.Where("records.is_closed = ?", request.Status == 2)
+	}
+	if request.Title != "" {
+		query = query.
+			Joins("JOIN books ON books.id = records.book_id").
+			Joins("JOIN book_types ON book_types.id = books.book_type_id").
+			Where(containsFold("book_types.title", request.Title))
+	}
+	// Count and page share the filters
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	page := query.
+		Preload("User").
+		Preload("Book", withDeleted).
+		Preload("Book.BookType", withDeleted).
+		Order("records.id")
+	if after != nil {
+		page = page.Where("records.id > ?", after.ID)
+	} else {
+		page = page.Offset(request.Offset())
+	}
+
+	var records []models.Record
+	limit := request.Limit()
+	if err := page.Limit(limit + 1).Find(&records).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	records, info := pageInfo(records, limit, total, func(record models.Record) cursor {
+		return cursor{Sort: "id", ID: record.ID}
+	})
+	return records, info, nil
+}
+
+func (r *gormRecordRepository) FindByIDs(ids []uint) ([]models.Record, error) {
+	var records []models.Record
+	err := r.db.Preload("Book").Where("id IN ?", ids).Find(&records).Error
+	return records, err
+}
+
+func (r *gormRecordRepository) FindOpenByCopy(bookID uint) (models.Record, error) {
+	var record models.Record
+	err := r.db.Where("book_id = ? AND is_closed = ?", bookID, false).First(&record).Error
+	return record, err
+}
+
+func (r *gormRecordRepository) OpenDueDates(bookIDs []uint) (map[uint]time.Time, error) {
+	var records []models.Record
+	if err := r.db.Select("book_id", "due_at").
+		Where("book_id IN ? AND is_closed = ?", bookIDs, false).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+	dueDates := make(map[uint]time.Time, len(records))
+	for _, record := range records {
+		dueDates[record.BookID] = record.DueAt
+	}
+	return dueDates, nil
+}
+
+func (r *gormRecordRepository) CountOpen(userIDs ...uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Record{}).
+		Where("user_id IN ? AND is_closed = ?", userIDs, false).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormRecordRepository) CountLoans(now time.Time) (active, overdue int64, err error) {
+	open := func() *gorm.DB {
+		return r.db.Model(&models.Record{}).Where("is_closed = ?", false)
+	}
+	if err := open().Count(&active).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := open().Where("due_at < ?", now).Count(&overdue).Error; err != nil {
+		return 0, 0, err
+	}
+	return active, overdue, nil
+}
+
+func (r *gormRecordRepository) Create(records []models.Record) error {
+	return r.db.Omit("User", "Book").Create(&records).Error
+}
+
+func (r *gormRecordRepository) Close(ids []uint, returnedAt time.Time) ([]models.Record, error) {
+	// One row at a time, so a record a concurrent return got to first is
+	// not closed, fined or released a second time
+	var closed []uint
+	for _, id := range ids {
+		result := r.db.Model(&models.Record{}).
+			Where("id = ? AND is_closed = ?", id, false).
+			Updates(map[string]interface{}{"returned_at": returnedAt, "is_closed": true})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			closed = append(closed, id)
+		}
+	}
+	if len(closed) == 0 {
+		return nil, nil
+	}
+	var records []models.Record
+	err := r.db.Where("id IN ?", closed).Order("id").Find(&records).Error
+	return records, err
+}
+
+func (r *gormRecordRepository) Renew(id uint, renewalCount int, dueAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Record{}).
+		Where("id = ? AND is_closed = ? AND renewal_count = ?", id, false, renewalCount).
+		Updates(map[string]interface{}{
+			"due_at":        dueAt,
+			"renewal_count": renewalCount + 1,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormRecordRepository) DeleteClosed(userID uint, deletedAt time.Time) error {
+	return r.db.Model(&models.Record{}).
+		Where("user_id = ? AND is_closed = ?", userID, true).
+		Update("deleted_at", deletedAt).Error
+}
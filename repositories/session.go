@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"library/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *models.Session) error
+	ListActive(userID uint, now time.Time) ([]models.Session, error)
+	Touch(id uint, usedAt time.Time) error
+	// Revoke revokes the given sessions of the user, or all of them when ids is nil
+	Revoke(userID uint, ids []uint, revokedAt time.Time) error
+
+	CreateRefreshToken(token *models.RefreshToken) error
+	// FindRefreshToken loads a refresh token by hash with its session
+	FindRefreshToken(tokenHash string) (models.RefreshToken, error)
+	// UseRefreshToken marks an unused token used, and reports whether it was still unused
+	UseRefreshToken(id uint, usedAt time.Time) (bool, error)
+}
+
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSessionRepository) Create(session *models.Session) error {
+	return r.db.Omit("User").Create(session).Error
+}
+
+func (r *gormSessionRepository) ListActive(userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *gormSessionRepository) Touch(id uint, usedAt time.Time) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
+
+func (r *gormSessionRepository) Revoke(userID uint, ids []uint, revokedAt time.Time) error {
+	query := r.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+	return query.Update("revoked_at", revokedAt).Error
+}
+
+func (r *gormSessionRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Omit("Session").Create(token).Error
+}
+
+func (r *gormSessionRepository) FindRefreshToken(tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Preload("Session").Where("token_hash = ?", tokenHash).First(&token).Error
+	return token, err
+}
+
+func (r *gormSessionRepository) UseRefreshToken(id uint, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,74 @@
+// Package repositories hides the SQL behind small interfaces so the rules in
+// services can run against the database or against in-memory fakes.
+package repositories
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Errors every implementation returns, shared with gorm so callers can keep
+// using errors.Is against either
+var (
+	ErrNotFound  = gorm.ErrRecordNotFound
+	ErrDuplicate = gorm.ErrDuplicatedKey
+)
+
+// ErrInvalidCursor means a list cursor was not issued for that listing
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Store groups the repositories that have to change together
+type Store interface {
+	Books() BookRepository
+	Records() RecordRepository
+	Users() UserRepository
+	Sessions() SessionRepository
+	Holds() HoldRepository
+	Fines() FineRepository
+	Policies() PolicyRepository
+	Audit() AuditRepository
+	Trash() TrashRepository
+
+	// Transaction runs fn against a store whose changes commit together,
+	// or not at all when fn returns an error
+	Transaction(fn func(store Store) error) error
+}
+
+type GormStore struct {
+	DB *gorm.DB
+}
+
+// Constructor function to create a new GormStore, db may be a transaction
+func NewStore(db *gorm.DB) *GormStore {
+	return &GormStore{DB: db}
+}
+
+func (s *GormStore) Books() BookRepository       { return &gormBookRepository{db: s.DB} }
+func (s *GormStore) Records() RecordRepository   { return &gormRecordRepository{db: s.DB} }
+func (s *GormStore) Users() UserRepository       { return &gormUserRepository{db: s.DB} }
+func (s *GormStore) Sessions() SessionRepository { return &gormSessionRepository{db: s.DB} }
+func (s *GormStore) Holds() HoldRepository       { return &gormHoldRepository{db: s.DB} }
+func (s *GormStore) Fines() FineRepository       { return &gormFineRepository{db: s.DB} }
+func (s *GormStore) Policies() PolicyRepository  { return &gormPolicyRepository{db: s.DB} }
+func (s *GormStore) Audit() AuditRepository      { return &gormAuditRepository{db: s.DB} }
+func (s *GormStore) Trash() TrashRepository      { return &gormTrashRepository{db: s.DB} }
+
+func (s *GormStore) Transaction(fn func(store Store) error) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(NewStore(tx))
+	})
+}
+
+// containsFold matches rows whose column contains the term, ignoring case,
+// in SQL both postgres and sqlite understand
+func containsFold(column, term string) (string, string) {
+	return "LOWER(" + column + ") LIKE ?", "%" + strings.ToLower(term) + "%"
+}
+
+// withDeleted lets a preload reach rows that were soft deleted since, so
+// history keeps its titles
+func withDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
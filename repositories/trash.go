@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"fmt"
+	"library/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrashRepository reaches the soft deleted users, titles, copies and records.
+// The Deleted* lookups report ErrNotFound for rows that are not deleted.
+type TrashRepository interface {
+	// List pages through the deleted rows of one kind, most recently deleted first
+	List(kind string, page models.Pagination) ([]models.TrashItem, int64, error)
+	DeletedUser(id uint) (models.User, error)
+	DeletedBookType(id uint) (models.BookType, error)
+	DeletedCopy(id uint) (models.Book, error)
+	DeletedRecord(id uint) (models.Record, error)
+	// RestoreUser brings a user back along with the records deleted at or after since
+	RestoreUser(id uint, since time.Time) error
+	// RestoreBookType brings a title back along with the copies deleted at or
+	// after since, and returns those copies
+	RestoreBookType(id uint, since time.Time) ([]models.Book, error)
+	RestoreCopy(id uint) error
+	RestoreRecord(id uint) error
+}
+
+type gormTrashRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTrashRepository) List(kind string, page models.Pagination) ([]models.TrashItem, int64, error) {
+	var model interface{}
+	switch kind {
+	case models.TrashUsers:
+		model = &models.User{}
+	case models.TrashTitles:
+		model = &models.BookType{}
+	case models.TrashCopies:
+		model = &models.Book{}
+	case models.TrashRecords:
+		model = &models.Record{}
+	default:
+		return nil, 0, fmt.Errorf("unknown trash type %q", kind)
+	}
+
+	var total int64
+	if err := r.db.Unscoped().Model(model).Where("deleted_at IS NOT NULL").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Order("deleted_at DESC")
+
+	var items []models.TrashItem
+	switch kind {
+	case models.TrashUsers:
+		var users []models.User
+		if err := query.Find(&users).Error; err != nil {
+			return nil, 0, err
+		}
+		for _, user := range users {
+			items = append(items, models.TrashItem{ID: user.ID, Label: user.Username, DeletedAt: user.DeletedAt.Time})
+		}
+	case models.TrashTitles:
+		var bookTypes []models.BookType
+		if err := query.Find(&bookTypes).Error; err != nil {
+			return nil, 0, err
+		}
+		for _, bookType := range bookTypes {
+			items = append(items, models.TrashItem{ID: bookType.ID, Label: bookType.Title, DeletedAt: bookType.DeletedAt.Time})
+		}
+	case models.TrashCopies:
+		var books []models.Book
+		if err := query.Preload("BookType", withDeleted).Find(&books).Error; err != nil {
+			return nil, 0, err
+		}
+		for _, book := range books {
+			label := book.BookType.Title
+			if book.Barcode != nil {
+				label = *book.Barcode + " " + label
+			}
+			items = append(items, models.TrashItem{ID: book.ID, Label: label, DeletedAt: book.DeletedAt.Time})
+		}
+	case models.TrashRecords:
+		var records []models.Record
+		if err := query.Preload("User", withDeleted).
+			Preload("Book", withDeleted).
+			Preload("Book.BookType", withDeleted).
+			Find(&records).Error; err != nil {
+			return nil, 0, err
+		}
+		for _, record := range records {
+			label := fmt.Sprintf("%s borrowed by %s", record.Book.BookType.Title, record.User.Username)
+			items = append(items, models.TrashItem{ID: record.ID, Label: label, DeletedAt: record.DeletedAt.Time})
+		}
+	}
+	return items, total, nil
+}
+
+func (r *gormTrashRepository) DeletedUser(id uint) (models.User, error) {
+	var user models.User
+	err := r.findDeleted(&user, id)
+	return user, err
+}
+
+func (r *gormTrashRepository) DeletedBookType(id uint) (models.BookType, error) {
+	var bookType models.BookType
+	err := r.findDeleted(&bookType, id)
+	return bookType, err
+}
+
+func (r *gormTrashRepository) DeletedCopy(id uint) (models.Book, error) {
+	var book models.Book
+	err := r.findDeleted(&book, id)
+	return book, err
+}
+
+func (r *gormTrashRepository) DeletedRecord(id uint) (models.Record, error) {
+	var record models.Record
+	err := r.findDeleted(&record, id)
+	return record, err
+}
+
+func (r *gormTrashRepository) findDeleted(dest interface{}, id uint) error {
+	return r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(dest).Error
+}
+
+func (r *gormTrashRepository) RestoreUser(id uint, since time.Time) error {
+	if err := r.db.Unscoped().Model(&models.User{}).Where("id = ?", id).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	return r.db.Unscoped().Model(&models.Record{}).
+		Where("user_id = ? AND deleted_at >= ?", id, since).
+		Update("deleted_at", nil).Error
+}
+
+func (r *gormTrashRepository) RestoreBookType(id uint, since time.Time) ([]models.Book, error) {
+	if err := r.db.Unscoped().Model(&models.BookType{}).Where("id = ?", id).Update("deleted_at", nil).Error; err != nil {
+		return nil, err
+	}
+
+	var books []models.Book
+	if err := r.db.Unscoped().
+		Where("book_type_id = ? AND deleted_at >= ?", id, since).
+		Find(&books).Error; err != nil {
+		return nil, err
+	}
+	for _, book := range books {
+		if err := r.RestoreCopy(book.ID); err != nil {
+			return nil, err
+		}
+	}
+	return books, nil
+}
+
+func (r *gormTrashRepository) RestoreCopy(id uint) error {
+	return r.db.Unscoped().Model(&models.Book{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+func (r *gormTrashRepository) RestoreRecord(id uint) error {
+	return r.db.Unscoped().Model(&models.Record{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"library/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	FindByID(id uint) (models.User, error)
+	FindByUsername(username string) (models.User, error)
+	// UsernameTaken also counts deleted users, who keep their username until purged
+	UsernameTaken(username string) (bool, error)
+	Create(user *models.User) error
+	// UpdateRole and UpdateCategory report whether the user exists
+	UpdateRole(id uint, role string) (bool, error)
+	UpdateCategory(id uint, category string) (bool, error)
+	UpdateProfile(id uint, nickname, email, phone string) error
+	UpdatePassword(id uint, passwordHash string) error
+	Delete(id uint, deletedAt time.Time) error
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) FindByID(id uint) (models.User, error) {
+	var user models.User
+	err := r.db.Where("id = ?", id).First(&user).Error
+	return user, err
+}
+
+func (r *gormUserRepository) FindByUsername(username string) (models.User, error) {
+	var user models.User
+	err := r.db.Where("username = ?", username).First(&user).Error
+	return user, err
+}
+
+func (r *gormUserRepository) UsernameTaken(username string) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormUserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *gormUserRepository) UpdateRole(id uint, role string) (bool, error) {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("role", role)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormUserRepository) UpdateCategory(id uint, category string) (bool, error) {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("category", category)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormUserRepository) UpdateProfile(id uint, nickname, email, phone string) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"nickname": nickname, "email": email, "phone": phone}).Error
+}
+
+func (r *gormUserRepository) UpdatePassword(id uint, passwordHash string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password", passwordHash).Error
+}
+
+func (r *gormUserRepository) Delete(id uint, deletedAt time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("deleted_at", deletedAt).Error
+}
@@ -0,0 +1,159 @@
+// Package routes registers every endpoint of the API, so the server and the
+// tests serve the same routes.
+package routes
+
+import (
+	"library/config"
+	"library/controllers"
+	"library/metrics"
+	"library/middlewares"
+	"library/models"
+	"library/openapi"
+	"library/repositories"
+	"library/search"
+	"library/services"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Deps are what the handlers are built from
+type Deps struct {
+	DB      *gorm.DB
+	Store   repositories.Store
+	Auth    config.AuthConfig
+	Fines   models.FinePolicy
+	Health  *controllers.HealthController
+	Metrics *metrics.Metrics
+	Log     *slog.Logger
+}
+
+// Auth authenticates the requests of each route group. Session routes act on
+// the session behind the token, patron routes on the signed in user and staff
+// routes on the user the role checks then apply to.
+type Auth struct {
+	Session gin.HandlerFunc
+	Patron  gin.HandlerFunc
+	Staff   gin.HandlerFunc
+}
+
+// RegisterRoutes adds every endpoint to the router
+func RegisterRoutes(router *gin.Engine, deps Deps, auth Auth) {
+	logger := deps.Log
+	store := deps.Store
+	circulation := services.NewCirculationService(store, deps.Fines, logger)
+
+	router.GET("/healthz", deps.Health.Liveness)
+	router.GET("/readyz", deps.Health.Readiness)
+	router.GET("/metrics", deps.Metrics.Handler())
+	router.GET("/openapi.json", openapi.Handler())
+	router.GET("/docs", openapi.UI("/openapi.json"))
+
+	userController := controllers.NewUserController(store, deps.Auth, logger)
+	userRouter := router.Group("/user")
+	{
+		userRouter.POST("/signup", userController.CreateUser)
+		userRouter.POST("/signin", userController.SignIn)
+		userRouter.POST("/refresh", userController.RefreshToken)
+		userRouter.GET("/info", auth.Patron, userController.GetUserInfo)
+		userRouter.POST("/logout", auth.Session, userController.Logout)
+		userRouter.POST("/logout-all", auth.Session, userController.LogoutAll)
+		userRouter.POST("/sessions", auth.Session, userController.GetSessionList)
+		userRouter.POST("/sessions/revoke", auth.Session, userController.RevokeSessions)
+
+		adminUserRouter := userRouter.Group("", auth.Staff, middlewares.RequireRole(models.RoleAdmin))
+		adminUserRouter.POST("/role", userController.UpdateUserRole)
+		adminUserRouter.POST("/category", userController.UpdateUserCategory)
+		adminUserRouter.POST("/revoke-sessions", userController.RevokeUserSessions)
+		adminUserRouter.POST("/delete", userController.DeleteUsers)
+	}
+
+	deprecated := middlewares.Deprecated()
+	bookController := controllers.NewBookController(store, circulation, logger, deps.Metrics)
+	bookRouter := router.Group("/book", deprecated)
+	{
+		bookRouter.POST("/list", bookController.GetBookList)
+		bookRouter.POST("/borrow", auth.Patron, bookController.BorrowBooks)
+	}
+
+	recordController := controllers.NewRecordController(store, circulation, logger, deps.Metrics)
+	recordRouter := router.Group("/record", deprecated)
+	{
+		recordRouter.POST("/list", auth.Patron, recordController.GetRecordList)
+		recordRouter.POST("/extend", auth.Patron, recordController.ExtendRecords)
+		recordRouter.POST("/return", auth.Patron, recordController.ReturnRecords)
+	}
+
+	searchController := controllers.NewSearchController(search.New(deps.DB, store.Books()), logger)
+
+	// REST routes sharing the handlers of /book and /record, which stay as
+	// deprecated aliases
+	v1Router := router.Group("/api/v1")
+	{
+		v1Router.GET("/books", bookController.GetBookList)
+		v1Router.GET("/books/:id", bookController.GetBook)
+		v1Router.GET("/search", searchController.SearchBooks)
+		v1Router.POST("/loans", auth.Patron, bookController.BorrowBooks)
+		v1Router.PATCH("/loans/:id", auth.Patron, recordController.ExtendRecords)
+		v1Router.POST("/loans/:id/return", auth.Patron, recordController.ReturnRecords)
+		v1Router.GET("/me/loans", auth.Patron, recordController.GetRecordList)
+		v1Router.GET("/me", auth.Patron, userController.GetProfile)
+		v1Router.PATCH("/me", auth.Patron, userController.UpdateProfile)
+		v1Router.DELETE("/me", auth.Patron, userController.DeleteAccount)
+		v1Router.POST("/me/password", auth.Patron, userController.ChangePassword)
+	}
+
+	holdController := controllers.NewHoldController(store, logger)
+	holdRouter := router.Group("/hold")
+	{
+		holdRouter.POST("/place", auth.Patron, holdController.PlaceHolds)
+		holdRouter.POST("/list", auth.Patron, holdController.GetHoldList)
+		holdRouter.POST("/cancel", auth.Patron, holdController.CancelHolds)
+	}
+
+	fineController := controllers.NewFineController(store, logger)
+	fineRouter := router.Group("/fine")
+	{
+		fineRouter.POST("/list", auth.Patron, fineController.GetFineList)
+
+		staffFineRouter := fineRouter.Group("", auth.Staff, middlewares.RequireRole(models.RoleLibrarian, models.RoleAdmin))
+		staffFineRouter.POST("/pay", fineController.PayFines)
+		staffFineRouter.POST("/waive", fineController.WaiveFines)
+	}
+
+	catalogController := controllers.NewCatalogController(store, logger)
+	catalogRouter := router.Group("/catalog", auth.Staff, middlewares.RequireRole(models.RoleLibrarian, models.RoleAdmin))
+	{
+		catalogRouter.POST("/create", catalogController.CreateBookType)
+		catalogRouter.POST("/update", catalogController.UpdateBookType)
+		catalogRouter.POST("/delete", catalogController.DeleteBookTypes)
+		catalogRouter.POST("/copies/add", catalogController.AddCopies)
+		catalogRouter.POST("/copies/withdraw", catalogController.WithdrawCopy)
+		catalogRouter.POST("/copies/move", catalogController.MoveCopy)
+	}
+
+	circulationController := controllers.NewCirculationController(circulation, logger, deps.Metrics)
+	circulationRouter := router.Group("/circulation", auth.Staff, middlewares.RequireRole(models.RoleLibrarian, models.RoleAdmin))
+	{
+		circulationRouter.POST("/checkout", circulationController.CheckOut)
+		circulationRouter.POST("/checkin", circulationController.CheckIn)
+	}
+
+	policyController := controllers.NewPolicyController(store, logger)
+	policyRouter := router.Group("/policy", auth.Staff, middlewares.RequireRole(models.RoleLibrarian, models.RoleAdmin))
+	{
+		policyRouter.POST("/list", policyController.GetPolicyList)
+
+		adminPolicyRouter := policyRouter.Group("", middlewares.RequireRole(models.RoleAdmin))
+		adminPolicyRouter.POST("/save", policyController.SavePolicy)
+		adminPolicyRouter.POST("/delete", policyController.DeletePolicies)
+	}
+
+	trashController := controllers.NewTrashController(store, logger)
+	trashRouter := router.Group("/trash", auth.Staff, middlewares.RequireRole(models.RoleAdmin))
+	{
+		trashRouter.POST("/list", trashController.GetTrashList)
+		trashRouter.POST("/restore", trashController.RestoreTrash)
+	}
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"library/models"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// memoryEngine keeps an inverted index of the catalog for databases without
+// full-text search. It rebuilds the index when titles are added, changed or
+// deleted, or an author or subject is renamed; editing a title's authors or
+// subjects saves the title too.
+type memoryEngine struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	signature string
+	index     *memoryIndex
+}
+
+type memoryIndex struct {
+	// word -> title -> weight of the heaviest field holding the word
+	postings map[string]map[uint]float64
+	titles   map[uint]match
+}
+
+func (e *memoryEngine) match(ctx context.Context, query string, terms []string) ([]match, error) {
+	index, err := e.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every term has to match some word of the title, the best match counts
+	var scores map[uint]float64
+	for _, term := range terms {
+		termScores := map[uint]float64{}
+		for word, titles := range index.postings {
+			quality := matchQuality(term, word)
+			if quality == 0 {
+				continue
+			}
+			for id, weight := range titles {
+				termScores[id] = max(termScores[id], quality*weight)
+			}
+		}
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if termScore, ok := termScores[id]; ok {
+				scores[id] += termScore
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	matches := make([]match, 0, len(scores))
+	for id, score := range scores {
+		m := index.titles[id]
+		m.Score = score
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// current returns the index, rebuilding it when the catalog changed
+func (e *memoryEngine) current(ctx context.Context) (*memoryIndex, error) {
+	var count int64
+	var updatedAt, authorsUpdatedAt, subjectsUpdatedAt sql.NullString
+	err := e.db.WithContext(ctx).Model(&models.BookType{}).
+		Select("COUNT(*), MAX(updated_at)").Row().Scan(&count, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = e.db.WithContext(ctx).Model(&models.Author{}).Select("MAX(updated_at)").Row().Scan(&authorsUpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = e.db.WithContext(ctx).Model(&models.Subject{}).Select("MAX(updated_at)").Row().Scan(&subjectsUpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	signature := fmt.Sprintf("%d:%s:%s:%s", count, updatedAt.String, authorsUpdatedAt.String, subjectsUpdatedAt.String)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.index != nil && e.signature == signature {
+		return e.index, nil
+	}
+
+	var bookTypes []models.BookType
+	if err := e.db.WithContext(ctx).Preload("Authors").Preload("Subjects").Find(&bookTypes).Error; err != nil {
+		return nil, err
+	}
+	e.index = buildIndex(bookTypes)
+	e.signature = signature
+	return e.index, nil
+}
+
+func buildIndex(bookTypes []models.BookType) *memoryIndex {
+	index := &memoryIndex{
+		postings: map[string]map[uint]float64{},
+		titles:   make(map[uint]match, len(bookTypes)),
+	}
+	add := func(id uint, text string, weight float64) {
+		for _, word := range tokenize(text) {
+			titles := index.postings[word]
+			if titles == nil {
+				titles = map[uint]float64{}
+				index.postings[word] = titles
+			}
+			titles[id] = max(titles[id], weight)
+		}
+	}
+
+	for _, bookType := range bookTypes {
+		index.titles[bookType.ID] = match{ID: bookType.ID, Language: bookType.Language, Year: bookType.PublicationYear}
+		add(bookType.ID, bookType.Title, weightTitle)
+		for _, author := range bookType.Authors {
+			add(bookType.ID, author.Name, weightAuthor)
+		}
+		for _, subject := range bookType.Subjects {
+			add(bookType.ID, subject.Heading, weightSubject)
+		}
+		add(bookType.ID, bookType.Description, weightDescription)
+	}
+	return index
+}
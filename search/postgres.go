@@ -0,0 +1,27 @@
+package search
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// postgresEngine matches against book_types.search_vector, kept current by
+// triggers: title weighted A, authors B, subjects C and description D. Words
+// the full-text query misses, typos included, are caught by trigram
+// similarity on search_text.
+type postgresEngine struct {
+	db *gorm.DB
+}
+
+const postgresMatch = `
+SELECT id, language, publication_year AS year,
+	ts_rank_cd('{0.1, 0.2, 0.4, 1.0}', search_vector, query) + word_similarity(@q, search_text) / 2 AS score
+FROM book_types, websearch_to_tsquery('simple', @q) AS query
+WHERE deleted_at IS NULL AND (search_vector @@ query OR @q <% search_text)`
+
+func (e *postgresEngine) match(ctx context.Context, query string, terms []string) ([]match, error) {
+	var matches []match
+	err := e.db.WithContext(ctx).Raw(postgresMatch, map[string]any{"q": query}).Scan(&matches).Error
+	return matches, err
+}
@@ -0,0 +1,203 @@
+// Package search ranks catalog titles against free text over their title,
+// authors, subjects and description, tolerating typos. Postgres matches with
+// its full-text search; other databases use an index kept in memory. Both
+// feed the same filtering, facets and highlighting, so results look alike.
+package search
+
+import (
+	"context"
+	"library/models"
+	"library/repositories"
+	"math"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Relative weight of a match in each field, as Postgres ranks A to D
+	weightTitle       = 1.0
+	weightAuthor      = 0.4
+	weightSubject     = 0.2
+	weightDescription = 0.1
+)
+
+// match is one title matching the query with the fields facets need
+type match struct {
+	ID       uint
+	Score    float64
+	Language string
+	Year     int
+}
+
+// engine finds every title matching the query, in no particular order
+type engine interface {
+	match(ctx context.Context, query string, terms []string) ([]match, error)
+}
+
+type Searcher struct {
+	engine engine
+	books  repositories.BookRepository
+}
+
+// Constructor function to create the Searcher for db's dialect
+func New(db *gorm.DB, books repositories.BookRepository) *Searcher {
+	var e engine
+	if db.Dialector.Name() == "postgres" {
+		e = &postgresEngine{db: db}
+	} else {
+		e = &memoryEngine{db: db}
+	}
+	return &Searcher{engine: e, books: books}
+}
+
+func (s *Searcher) Search(ctx context.Context, request models.SearchRequest) (models.SearchResponse, error) {
+	response := models.SearchResponse{
+		Results: []models.SearchHit{},
+		Facets: models.SearchFacets{
+			Languages:    []models.FacetCount{},
+			Years:        []models.YearFacet{},
+			Availability: []models.FacetCount{},
+		},
+	}
+	terms := tokenize(request.Query)
+	if len(terms) == 0 {
+		return response, nil
+	}
+
+	matches, err := s.engine.match(ctx, request.Query, terms)
+	if err != nil || len(matches) == 0 {
+		return response, err
+	}
+	ids := make([]uint, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+	totalCounts, availableCounts, err := s.books.CopyCounts(ids)
+	if err != nil {
+		return response, err
+	}
+
+	// Facets count what each filter would leave if it alone were lifted
+	language := strings.ToLower(request.Language)
+	languages := map[string]int{}
+	decades := map[int]int{}
+	availability := map[string]int{}
+	var hits []match
+	for _, m := range matches {
+		languageOK := language == "" || m.Language == language
+		yearOK := (request.YearFrom == 0 || m.Year >= request.YearFrom) && (request.YearTo == 0 || m.Year <= request.YearTo)
+		available := availableCounts[m.ID] > 0
+		availableOK := !request.Available || available
+
+		if yearOK && availableOK && m.Language != "" {
+			languages[m.Language]++
+		}
+		if languageOK && availableOK && m.Year > 0 {
+			decades[m.Year/10*10]++
+		}
+		if languageOK && yearOK {
+			if available {
+				availability["available"]++
+			} else {
+				availability["unavailable"]++
+			}
+		}
+		if languageOK && yearOK && availableOK {
+			hits = append(hits, m)
+		}
+	}
+	response.Facets = facets(languages, decades, availability)
+	response.Total = len(hits)
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	from := min(request.Offset(), len(hits))
+	page := hits[from:min(from+request.Limit(), len(hits))]
+	if len(page) == 0 {
+		return response, nil
+	}
+
+	pageIDs := make([]uint, len(page))
+	for i, m := range page {
+		pageIDs[i] = m.ID
+	}
+	bookTypes, err := s.books.FindBookTypesDetails(pageIDs)
+	if err != nil {
+		return response, err
+	}
+	byID := make(map[uint]models.BookType, len(bookTypes))
+	for _, bookType := range bookTypes {
+		byID[bookType.ID] = bookType
+	}
+	for _, m := range page {
+		bookType, ok := byID[m.ID]
+		if !ok {
+			continue // deleted since it was matched
+		}
+		response.Results = append(response.Results, models.SearchHit{
+			Book:       bookType.ToResponse(totalCounts[m.ID], availableCounts[m.ID]),
+			Score:      math.Round(m.Score*10000) / 10000,
+			Highlights: highlights(bookType, terms),
+		})
+	}
+	return response, nil
+}
+
+func facets(languages map[string]int, decades map[int]int, availability map[string]int) models.SearchFacets {
+	result := models.SearchFacets{
+		Languages:    []models.FacetCount{},
+		Years:        []models.YearFacet{},
+		Availability: []models.FacetCount{},
+	}
+	for value, count := range languages {
+		result.Languages = append(result.Languages, models.FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(result.Languages, func(i, j int) bool {
+		if result.Languages[i].Count != result.Languages[j].Count {
+			return result.Languages[i].Count > result.Languages[j].Count
+		}
+		return result.Languages[i].Value < result.Languages[j].Value
+	})
+	for decade, count := range decades {
+		result.Years = append(result.Years, models.YearFacet{From: decade, To: decade + 9, Count: count})
+	}
+	sort.Slice(result.Years, func(i, j int) bool { return result.Years[i].From < result.Years[j].From })
+	for _, value := range []string{"available", "unavailable"} {
+		if count := availability[value]; count > 0 {
+			result.Availability = append(result.Availability, models.FacetCount{Value: value, Count: count})
+		}
+	}
+	return result
+}
+
+// highlights marks the query terms in every field of the title they appear in
+func highlights(bookType models.BookType, terms []string) map[string]string {
+	result := map[string]string{}
+	if marked, ok := highlight(bookType.Title, terms); ok {
+		result["title"] = marked
+	}
+	authors := make([]string, len(bookType.Authors))
+	for i, author := range bookType.Authors {
+		authors[i] = author.Name
+	}
+	if marked, ok := highlight(strings.Join(authors, ", "), terms); ok {
+		result["authors"] = marked
+	}
+	subjects := make([]string, len(bookType.Subjects))
+	for i, subject := range bookType.Subjects {
+		subjects[i] = subject.Heading
+	}
+	if marked, ok := highlight(strings.Join(subjects, "; "), terms); ok {
+		result["subjects"] = marked
+	}
+	if marked, ok := snippet(bookType.Description, terms); ok {
+		result["description"] = marked
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Words around the first match kept in a description snippet
+const snippetWords = 24
+
+type span struct{ start, end int }
+
+// tokenSpans finds the words of s: runs of letters and digits
+func tokenSpans(s string) []span {
+	var spans []span
+	start := -1
+	for i, r := range s {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(s)})
+	}
+	return spans
+}
+
+// tokenize lower cases the words of s
+func tokenize(s string) []string {
+	spans := tokenSpans(s)
+	tokens := make([]string, 0, len(spans))
+	for _, sp := range spans {
+		tokens = append(tokens, strings.ToLower(s[sp.start:sp.end]))
+	}
+	return tokens
+}
+
+// matchQuality scores how well a query term matches a word: 1 when equal,
+// less for a prefix or a typo, 0 when it does not match
+func matchQuality(term, token string) float64 {
+	if term == token {
+		return 1
+	}
+	termLength := utf8.RuneCountInString(term)
+	if termLength >= 3 && strings.HasPrefix(token, term) {
+		return 0.75
+	}
+	if distance := maxDistance(termLength); distance > 0 && withinDistance(term, token, distance) {
+		return 0.5
+	}
+	return 0
+}
+
+// maxDistance is the number of typos tolerated in a term of the given length
+func maxDistance(length int) int {
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// withinDistance reports whether the Levenshtein distance between a and b is at most limit
+func withinDistance(a, b string, limit int) bool {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return false
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return false
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)] <= limit
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func matchesAny(token string, terms []string) bool {
+	for _, term := range terms {
+		if matchQuality(term, token) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// highlight escapes text for HTML and wraps the words matching terms in
+// <mark>. It reports whether anything matched.
+func highlight(text string, terms []string) (string, bool) {
+	var b strings.Builder
+	matched := false
+	last := 0
+	for _, sp := range tokenSpans(text) {
+		word := text[sp.start:sp.end]
+		if !matchesAny(strings.ToLower(word), terms) {
+			continue
+		}
+		matched = true
+		b.WriteString(html.EscapeString(text[last:sp.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(word))
+		b.WriteString("</mark>")
+		last = sp.end
+	}
+	if !matched {
+		return "", false
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String(), true
+}
+
+// snippet highlights the words of a long text around its first match
+func snippet(text string, terms []string) (string, bool) {
+	spans := tokenSpans(text)
+	first := -1
+	for i, sp := range spans {
+		if matchesAny(strings.ToLower(text[sp.start:sp.end]), terms) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	from := max(0, first-snippetWords/3)
+	to := min(len(spans), from+snippetWords)
+	start, end := spans[from].start, spans[to-1].end
+	if from == 0 {
+		start = 0
+	}
+	if to == len(spans) {
+		end = len(text)
+	}
+	marked, _ := highlight(text[start:end], terms)
+	if start > 0 {
+		marked = "…" + marked
+	}
+	if end < len(text) {
+		marked += "…"
+	}
+	return marked, true
+}
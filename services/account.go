@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library/repositories"
+	"log/slog"
+	"time"
+)
+
+// DeleteUser soft deletes a user along with their closed records, cancels
+// their holds and revokes every session. Open loans must be returned first.
+func DeleteUser(ctx context.Context, logger *slog.Logger, store repositories.Store, userID uint, now time.Time) error {
+	openLoans, err := store.Records().CountOpen(userID)
+	if err != nil {
+		return err
+	}
+	if openLoans > 0 {
+		return &RuleError{Err: ErrOpenLoans}
+	}
+
+	if err := store.Users().Delete(userID, now); err != nil {
+		return err
+	}
+	if err := store.Records().DeleteClosed(userID, now); err != nil {
+		return err
+	}
+
+	holds, err := store.Holds().Active(userID)
+	if err != nil {
+		return err
+	}
+	for _, hold := range holds {
+		// Holds expired or fulfilled in between have nothing left to cancel
+		hold, err := cancelHold(store, hold)
+		if errors.Is(err, ErrHoldClosed) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if hold.Status == 2 && hold.BookID != nil {
+			if err := ReleaseCopy(ctx, logger, store, *hold.BookID, hold.BookTypeID, 3, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	return store.Sessions().Revoke(userID, nil, now)
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library/models"
+	"library/repositories"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// CreateBookType adds a title to the catalog with count copies on the shelf
+func CreateBookType(ctx context.Context, logger *slog.Logger, store repositories.Store, bookType models.BookType, authors, subjects []string, count int, now time.Time) (models.BookType, error) {
+	var err error
+	if bookType.Authors, err = findOrCreateAuthors(store, authors); err != nil {
+		return models.BookType{}, fmt.Errorf("save authors: %w", err)
+	}
+	if bookType.Subjects, err = findOrCreateSubjects(store, subjects); err != nil {
+		return models.BookType{}, fmt.Errorf("save subjects: %w", err)
+	}
+	if err := store.Books().CreateBookType(&bookType); err != nil {
+		return models.BookType{}, fmt.Errorf("create book type: %w", err)
+	}
+	if _, err := addCopies(ctx, logger, store, bookType.ID, count, nil, "", now); err != nil {
+		return models.BookType{}, fmt.Errorf("create copies: %w", err)
+	}
+	return bookType, nil
+}
+
+// UpdateBookType saves a title's metadata and replaces its authors and
+// subjects, returning the title as saved
+func UpdateBookType(store repositories.Store, bookType models.BookType, authors, subjects []string) (models.BookType, error) {
+	if _, err := store.Books().FindBookType(bookType.ID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return models.BookType{}, &RuleError{Err: ErrBookNotFound, BookTypeID: bookType.ID}
+		}
+		return models.BookType{}, fmt.Errorf("fetch book type: %w", err)
+	}
+
+	// Keep updates from blanking the item type used by loan policies
+	if bookType.ItemType == "" {
+		bookType.ItemType = "book"
+	}
+	var err error
+	if bookType.Authors, err = findOrCreateAuthors(store, authors); err != nil {
+		return models.BookType{}, fmt.Errorf("save authors: %w", err)
+	}
+	if bookType.Subjects, err = findOrCreateSubjects(store, subjects); err != nil {
+		return models.BookType{}, fmt.Errorf("save subjects: %w", err)
+	}
+	if err := store.Books().UpdateBookType(bookType); err != nil {
+		return models.BookType{}, fmt.Errorf("update book type: %w", err)
+	}
+	return store.Books().FindBookTypeDetails(bookType.ID)
+}
+
+// DeleteBookTypes takes titles out of the catalog with all their copies and
+// cancels their holds. Titles with copies still out on loan stay.
+func DeleteBookTypes(store repositories.Store, bookTypeIDs []uint, now time.Time) error {
+	onLoan, err := store.Books().CountCopiesWithStatus(bookTypeIDs, 2)
+	if err != nil {
+		return fmt.Errorf("count copies on loan: %w", err)
+	}
+	if onLoan > 0 {
+		return ErrCopiesOnLoan
+	}
+
+	copies, err := store.Books().CountCopies(bookTypeIDs)
+	if err != nil {
+		return fmt.Errorf("count copies: %w", err)
+	}
+	withdrawn, err := store.Books().DeleteBookTypes(bookTypeIDs, now)
+	if err != nil {
+		return fmt.Errorf("delete book types: %w", err)
+	}
+	// A copy lent out since the count stays; the caller's transaction rolls
+	// the titles back
+	if withdrawn != copies {
+		return ErrCopiesOnLoan
+	}
+	// Nobody can pick these titles up any more
+	if err := store.Holds().CancelForTitles(bookTypeIDs); err != nil {
+		return fmt.Errorf("cancel holds: %w", err)
+	}
+	return nil
+}
+
+// AddCopies puts new copies of a title on the shelf, or on the hold shelf
+// for the patrons waiting for it. Copies without one of barcodes get a
+// barcode derived from their ID.
+func AddCopies(ctx context.Context, logger *slog.Logger, store repositories.Store, bookTypeID uint, count int, barcodes []string, shelfLocation string, now time.Time) ([]models.Book, error) {
+	if len(barcodes) > 0 {
+		taken, err := store.Books().BarcodesTaken(barcodes)
+		if err != nil {
+			return nil, fmt.Errorf("check barcodes: %w", err)
+		}
+		if taken > 0 {
+			return nil, ErrBarcodeTaken
+		}
+	}
+
+	if _, err := store.Books().FindBookType(bookTypeID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, &RuleError{Err: ErrBookNotFound, BookTypeID: bookTypeID}
+		}
+		return nil, fmt.Errorf("fetch book type: %w", err)
+	}
+	return addCopies(ctx, logger, store, bookTypeID, count, barcodes, shelfLocation, now)
+}
+
+// WithdrawCopy takes a copy on the shelf out of the collection
+func WithdrawCopy(store repositories.Store, bookID uint, now time.Time) error {
+	book, err := store.Books().FindCopy(bookID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrCopyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("fetch copy: %w", err)
+	}
+
+	switch book.Status {
+	case 2:
+		return ErrCopyCheckedOut
+	case 3:
+		return ErrCopyOnHoldShelf
+	case 4:
+		return ErrCopyWithdrawn
+	}
+
+	// Only withdraw if nobody borrowed it in the meantime
+	withdrawn, err := store.Books().WithdrawCopy(book.ID, now)
+	if err != nil {
+		return fmt.Errorf("withdraw copy: %w", err)
+	}
+	if !withdrawn {
+		return ErrCopyTaken
+	}
+	return nil
+}
+
+// MoveCopy changes the shelf a copy is kept on
+func MoveCopy(store repositories.Store, bookID uint, shelfLocation string) error {
+	moved, err := store.Books().MoveCopy(bookID, shelfLocation)
+	if err != nil {
+		return fmt.Errorf("move copy: %w", err)
+	}
+	if !moved {
+		return ErrCopyNotFound
+	}
+	return nil
+}
+
+func addCopies(ctx context.Context, logger *slog.Logger, store repositories.Store, bookTypeID uint, count int, barcodes []string, shelfLocation string, now time.Time) ([]models.Book, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	books := make([]models.Book, count)
+	for i := range books {
+		books[i] = models.Book{
+			BookTypeID:    bookTypeID,
+			Status:        1,
+			ShelfLocation: shelfLocation,
+		}
+		if i < len(barcodes) {
+			books[i].Barcode = &barcodes[i]
+		}
+	}
+	if err := store.Books().CreateCopies(books); err != nil {
+		return nil, err
+	}
+
+	bookIDs := make([]uint, len(books))
+	for i, book := range books {
+		if err := ReleaseCopy(ctx, logger, store, book.ID, bookTypeID, 1, now); err != nil {
+			return nil, err
+		}
+		bookIDs[i] = book.ID
+	}
+	return store.Books().FindCopies(bookIDs)
+}
+
+// findOrCreateAuthors looks up authors by name, creating the ones not seen before
+func findOrCreateAuthors(store repositories.Store, names []string) ([]models.Author, error) {
+	authors := []models.Author{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		author, err := store.Books().FindOrCreateAuthor(name)
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	return authors, nil
+}
+
+// findOrCreateSubjects looks up subject headings, creating the ones not seen before
+func findOrCreateSubjects(store repositories.Store, headings []string) ([]models.Subject, error) {
+	subjects := []models.Subject{}
+	seen := make(map[string]bool)
+	for _, heading := range headings {
+		heading = strings.TrimSpace(heading)
+		if heading == "" || seen[heading] {
+			continue
+		}
+		seen[heading] = true
+
+		subject, err := store.Books().FindOrCreateSubject(heading)
+		if err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, subject)
+	}
+	return subjects, nil
+}
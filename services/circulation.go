@@ -0,0 +1,531 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library/logging"
+	"library/models"
+	"library/repositories"
+	"log/slog"
+	"time"
+)
+
+// Number of days a copy stays on the hold shelf before the hold expires
+const HoldPickupDays = 7
+
+// Times a copy, record, fine or hold may be changed by another transaction
+// first before claiming, renewing, paying or cancelling it gives up
+const maxClaimAttempts = 10
+
+// CirculationService holds the borrow, renewal and return rules
+type CirculationService struct {
+	Store      repositories.Store
+	FinePolicy models.FinePolicy
+	Now        func() time.Time
+	Log        *slog.Logger
+}
+
+// Constructor function to create a new CirculationService
+func NewCirculationService(store repositories.Store, finePolicy models.FinePolicy, logger *slog.Logger) *CirculationService {
+	return &CirculationService{Store: store, FinePolicy: finePolicy, Now: time.Now, Log: logger}
+}
+
+// Borrow lends the user one copy of each title, preferring copies set aside for their holds
+func (s *CirculationService) Borrow(ctx context.Context, userID uint, bookTypeIDs []uint) ([]models.Record, error) {
+	if err := s.checkFines(userID); err != nil {
+		return nil, err
+	}
+
+	var records []models.Record
+	err := s.Store.Transaction(func(store repositories.Store) error {
+		now := s.Now()
+		if err := ExpireHolds(ctx, s.Log, store, now); err != nil {
+			return fmt.Errorf("expire holds: %w", err)
+		}
+
+		var books []models.Book
+		for _, bookTypeID := range bookTypeIDs {
+			// A copy set aside for the user's hold takes priority over the shelf
+			hold, err := store.Holds().ReadyFor(userID, bookTypeID)
+			if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+				return fmt.Errorf("fetch ready hold: %w", err)
+			}
+
+			var book models.Book
+			claimed := false
+			if err == nil && hold.BookID != nil {
+				book = models.Book{ID: *hold.BookID, BookTypeID: bookTypeID}
+				if claimed, err = store.Books().ClaimCopy(book.ID, 3); err != nil {
+					return fmt.Errorf("claim held copy: %w", err)
+				}
+			}
+
+			// Otherwise take a copy from the shelf
+			if !claimed {
+				book, err = claimShelfCopy(store, bookTypeID)
+				if errors.Is(err, repositories.ErrNotFound) || errors.Is(err, ErrCopyTaken) {
+					return &RuleError{Err: ErrNoCopyAvailable, BookTypeID: bookTypeID}
+				}
+				if err != nil {
+					return fmt.Errorf("claim shelf copy: %w", err)
+				}
+			}
+			books = append(books, book)
+		}
+
+		var err error
+		records, err = lend(store, userID, books, now)
+		return err
+	})
+	return records, err
+}
+
+// CheckOut lends a specific copy to a patron at the desk
+func (s *CirculationService) CheckOut(ctx context.Context, patronID uint, barcode string) (models.Record, error) {
+	if _, err := s.Store.Users().FindByID(patronID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return models.Record{}, ErrUserNotFound
+		}
+		return models.Record{}, fmt.Errorf("fetch user: %w", err)
+	}
+	if err := s.checkFines(patronID); err != nil {
+		return models.Record{}, err
+	}
+
+	var records []models.Record
+	err := s.Store.Transaction(func(store repositories.Store) error {
+		now := s.Now()
+		if err := ExpireHolds(ctx, s.Log, store, now); err != nil {
+			return fmt.Errorf("expire holds: %w", err)
+		}
+
+		book, err := store.Books().FindCopyByBarcode(barcode)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrCopyNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("fetch copy: %w", err)
+		}
+
+		switch book.Status {
+		case 2:
+			return ErrCopyCheckedOut
+		case 3:
+			// Copies on the hold shelf only go to the patron they were set aside for
+			held, err := store.Holds().CountReadyForCopy(patronID, book.ID)
+			if err != nil {
+				return fmt.Errorf("fetch ready hold: %w", err)
+			}
+			if held == 0 {
+				return ErrCopyHeldForOther
+			}
+		case 4:
+			return ErrCopyWithdrawn
+		}
+
+		claimed, err := store.Books().ClaimCopy(book.ID, book.Status)
+		if err != nil {
+			return fmt.Errorf("claim copy: %w", err)
+		}
+		if !claimed {
+			return ErrCopyTaken
+		}
+
+		records, err = lend(store, patronID, []models.Book{book}, now)
+		return err
+	})
+	if err != nil {
+		return models.Record{}, err
+	}
+	return records[0], nil
+}
+
+// Renew pushes the due date of each record back by its policy's renewal period
+func (s *CirculationService) Renew(ctx context.Context, userID uint, recordIDs []uint) ([]models.Record, error) {
+	if err := s.checkFines(userID); err != nil {
+		return nil, err
+	}
+
+	var renewed []models.Record
+	err := s.Store.Transaction(func(store repositories.Store) error {
+		records, err := store.Records().FindByIDs(recordIDs)
+		if err != nil {
+			return fmt.Errorf("fetch records: %w", err)
+		}
+		if missing := firstMissing(recordIDs, records); missing != 0 {
+			return &RuleError{Err: ErrRecordNotFound, RecordID: missing}
+		}
+		now := s.Now()
+		for _, record := range records {
+			record, err := renewRecord(store, userID, record, now)
+			if err != nil {
+				return err
+			}
+			renewed = append(renewed, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return renewed, nil
+}
+
+// Return closes the user's records
+func (s *CirculationService) Return(ctx context.Context, userID uint, recordIDs []uint) ([]models.Record, error) {
+	var returned []models.Record
+	err := s.Store.Transaction(func(store repositories.Store) error {
+		records, err := store.Records().FindByIDs(recordIDs)
+		if err != nil {
+			return fmt.Errorf("fetch records: %w", err)
+		}
+		if missing := firstMissing(recordIDs, records); missing != 0 {
+			return &RuleError{Err: ErrRecordNotFound, RecordID: missing}
+		}
+		for _, record := range records {
+			// Ensure all records belong to the user
+			if record.UserID != userID {
+				return &RuleError{Err: ErrNotOwner, RecordID: record.ID}
+			}
+			// Ensure all records are still open (not returned)
+			if record.IsClosed {
+				return &RuleError{Err: ErrRecordClosed, RecordID: record.ID}
+			}
+		}
+
+		returned, err = s.closeRecords(ctx, store, RecordIDs(records))
+		return err
+	})
+	return returned, err
+}
+
+// CheckIn closes the open record of a copy handed back at the desk
+func (s *CirculationService) CheckIn(ctx context.Context, barcode string) (models.Record, error) {
+	var returned []models.Record
+	err := s.Store.Transaction(func(store repositories.Store) error {
+		book, err := store.Books().FindCopyByBarcode(barcode)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrCopyNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("fetch copy: %w", err)
+		}
+
+		record, err := store.Records().FindOpenByCopy(book.ID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrNotCheckedOut
+		}
+		if err != nil {
+			return fmt.Errorf("fetch record: %w", err)
+		}
+
+		returned, err = s.closeRecords(ctx, store, []uint{record.ID})
+		// Checked in at another desk a moment ago
+		if errors.Is(err, ErrRecordClosed) {
+			return ErrNotCheckedOut
+		}
+		return err
+	})
+	if err != nil {
+		return models.Record{}, err
+	}
+	return returned[0], nil
+}
+
+func (s *CirculationService) checkFines(userID uint) error {
+	balance, err := s.Store.Fines().OutstandingBalance(userID)
+	if err != nil {
+		return fmt.Errorf("fetch fine balance: %w", err)
+	}
+	if balance > s.FinePolicy.BlockThreshold {
+		return &RuleError{Err: ErrFinesBlocked, Balance: balance}
+	}
+	return nil
+}
+
+// closeRecords closes the records, charges for late returns and hands each
+// copy to the next hold in the queue, or puts it back on the shelf
+func (s *CirculationService) closeRecords(ctx context.Context, store repositories.Store, recordIDs []uint) ([]models.Record, error) {
+	now := s.Now()
+	records, err := store.Records().Close(recordIDs, now)
+	if err != nil {
+		return nil, fmt.Errorf("close records: %w", err)
+	}
+	// A concurrent return closed some of them first; it fines and releases those
+	if len(records) < len(recordIDs) {
+		return nil, &RuleError{Err: ErrRecordClosed, RecordID: firstMissing(recordIDs, records)}
+	}
+
+	// Charge for anything returned late
+	if err := AssessFines(ctx, s.Log, store, records, s.FinePolicy); err != nil {
+		return nil, fmt.Errorf("assess fines: %w", err)
+	}
+
+	var bookIDs []uint
+	for _, record := range records {
+		bookIDs = append(bookIDs, record.BookID)
+	}
+	books, err := store.Books().FindCopies(bookIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fetch returned copies: %w", err)
+	}
+	for _, book := range books {
+		if err := ReleaseCopy(ctx, s.Log, store, book.ID, book.BookTypeID, 2, now); err != nil {
+			return nil, fmt.Errorf("release copy: %w", err)
+		}
+	}
+	return records, nil
+}
+
+// renewRecord renews one of the user's records. When another renewal of the
+// record lands in between, it is read again so the new due date builds on the
+// latest one and the renewal limit still holds.
+func renewRecord(store repositories.Store, userID uint, record models.Record, now time.Time) (models.Record, error) {
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		if attempt > 0 {
+			records, err := store.Records().FindByIDs([]uint{record.ID})
+			if err != nil {
+				return models.Record{}, fmt.Errorf("fetch record: %w", err)
+			}
+			if len(records) == 0 {
+				return models.Record{}, fmt.Errorf("fetch record %d: %w", record.ID, repositories.ErrNotFound)
+			}
+			record = records[0]
+		}
+
+		// Ensure the record belongs to the user
+		if record.UserID != userID {
+			return models.Record{}, &RuleError{Err: ErrNotOwner, RecordID: record.ID}
+		}
+		// Ensure the record is still open (not returned)
+		if record.IsClosed {
+			return models.Record{}, &RuleError{Err: ErrRecordClosed, RecordID: record.ID}
+		}
+		// Ensure the record is not overdue
+		if record.DueAt.Before(now) {
+			return models.Record{}, &RuleError{Err: ErrOverdue, RecordID: record.ID}
+		}
+		// Ensure the record has renewals left under its loan policy
+		policy, err := LoanPolicyFor(store, userID, record.Book.BookTypeID)
+		if err != nil {
+			return models.Record{}, fmt.Errorf("find loan policy: %w", err)
+		}
+		if record.RenewalCount >= policy.MaxRenewals {
+			return models.Record{}, &RuleError{Err: ErrRenewalLimitReached, RecordID: record.ID}
+		}
+
+		dueAt := record.DueAt.AddDate(0, 0, policy.RenewalDays)
+		renewed, err := store.Records().Renew(record.ID, record.RenewalCount, dueAt)
+		if err != nil {
+			return models.Record{}, fmt.Errorf("renew record: %w", err)
+		}
+		if renewed {
+			record.DueAt = dueAt
+			record.RenewalCount++
+			return record, nil
+		}
+	}
+	return models.Record{}, fmt.Errorf("renew record %d: changed by other requests %d times", record.ID, maxClaimAttempts)
+}
+
+// firstMissing finds the first of ids that is not among records
+func firstMissing(ids []uint, records []models.Record) uint {
+	found := make(map[uint]bool, len(records))
+	for _, record := range records {
+		found[record.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return id
+		}
+	}
+	return 0
+}
+
+// RecordIDs lists the ids of records in order
+func RecordIDs(records []models.Record) []uint {
+	ids := make([]uint, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	return ids
+}
+
+// lend opens a record for each copy already claimed for the user
+func lend(store repositories.Store, userID uint, books []models.Book, now time.Time) ([]models.Record, error) {
+	openLoans, err := store.Records().CountOpen(userID)
+	if err != nil {
+		return nil, fmt.Errorf("count open records: %w", err)
+	}
+
+	var bookTypeIDs []uint
+	var records []models.Record
+	for i, book := range books {
+		policy, err := LoanPolicyFor(store, userID, book.BookTypeID)
+		if err != nil {
+			return nil, fmt.Errorf("find loan policy: %w", err)
+		}
+		if policy.MaxLoans > 0 && int(openLoans)+i+1 > policy.MaxLoans {
+			return nil, ErrLoanLimitReached
+		}
+
+		bookTypeIDs = append(bookTypeIDs, book.BookTypeID)
+		records = append(records, models.Record{
+			UserID: userID,
+			BookID: book.ID,
+			DueAt:  now.AddDate(0, 0, policy.LoanDays),
+		})
+	}
+
+	// Any hold the user had on these titles is now fulfilled
+	if err := store.Holds().Fulfil(userID, bookTypeIDs); err != nil {
+		return nil, fmt.Errorf("fulfil holds: %w", err)
+	}
+
+	// The open record index rejects a second loan of the same copy
+	if err := store.Records().Create(records); err != nil {
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return nil, ErrCopyTaken
+		}
+		return nil, fmt.Errorf("create borrow records: %w", err)
+	}
+	return records, nil
+}
+
+// claimShelfCopy claims the first copy of a title on the shelf, moving on
+// to the next copy whenever a concurrent borrower wins the race
+func claimShelfCopy(store repositories.Store, bookTypeID uint) (models.Book, error) {
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		book, err := store.Books().FirstCopyWithStatus(bookTypeID, 1)
+		if err != nil {
+			return models.Book{}, err
+		}
+
+		claimed, err := store.Books().ClaimCopy(book.ID, 1)
+		if err != nil {
+			return models.Book{}, err
+		}
+		if claimed {
+			book.Status = 2
+			return book, nil
+		}
+	}
+	return models.Book{}, ErrCopyTaken
+}
+
+// ReleaseCopy sets a copy aside for the first waiting hold on its title,
+// or puts it back on the shelf when nobody is waiting. The copy must still
+// have fromStatus; when another request moved it first it fails with
+// ErrCopyTaken. A hold cancelled or made ready by another request in between
+// is skipped for the next one in the queue.
+func ReleaseCopy(ctx context.Context, logger *slog.Logger, store repositories.Store, bookID, bookTypeID, fromStatus uint, now time.Time) error {
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		hold, err := store.Holds().FirstWaiting(bookTypeID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return setCopyStatus(store, bookID, fromStatus, 1)
+		}
+		if err != nil {
+			return err
+		}
+
+		ready, err := store.Holds().MarkReady(hold.ID, bookID, now, now.AddDate(0, 0, HoldPickupDays))
+		if err != nil {
+			return err
+		}
+		if !ready {
+			continue
+		}
+		logging.Event(ctx, logger, logging.EventHoldReady, "Book set aside for hold",
+			slog.Uint64("book_id", uint64(bookID)), slog.Uint64("hold_id", uint64(hold.ID)), slog.Uint64("patron_id", uint64(hold.UserID)))
+		return setCopyStatus(store, bookID, fromStatus, 3)
+	}
+	return fmt.Errorf("release copy %d: queue changed by other requests %d times", bookID, maxClaimAttempts)
+}
+
+func setCopyStatus(store repositories.Store, bookID, fromStatus, status uint) error {
+	moved, err := store.Books().SetCopyStatus(bookID, fromStatus, status)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return ErrCopyTaken
+	}
+	return nil
+}
+
+// ExpireHolds closes ready holds whose pickup window has passed and passes
+// their copies on to the next patron in the queue. Holds cancelled or
+// expired by another request in between are left to it.
+func ExpireHolds(ctx context.Context, logger *slog.Logger, store repositories.Store, now time.Time) error {
+	holds, err := store.Holds().ExpiredReady(now)
+	if err != nil {
+		return err
+	}
+	for _, hold := range holds {
+		expired, err := store.Holds().Expire(hold.ID)
+		if err != nil {
+			return err
+		}
+		if !expired || hold.BookID == nil {
+			continue
+		}
+		logging.Event(ctx, logger, logging.EventHoldExpired, "Hold expired",
+			slog.Uint64("hold_id", uint64(hold.ID)), slog.Uint64("patron_id", uint64(hold.UserID)))
+		if err := ReleaseCopy(ctx, logger, store, *hold.BookID, hold.BookTypeID, 3, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoanPolicyFor picks the most specific policy for a user borrowing a title
+func LoanPolicyFor(store repositories.Store, userID, bookTypeID uint) (models.LoanPolicy, error) {
+	user, err := store.Users().FindByID(userID)
+	if err != nil {
+		return models.LoanPolicy{}, err
+	}
+	bookType, err := store.Books().FindBookType(bookTypeID)
+	if err != nil {
+		return models.LoanPolicy{}, err
+	}
+	policies, err := store.Policies().Candidates(user.Category, bookType.ItemType)
+	if err != nil {
+		return models.LoanPolicy{}, err
+	}
+
+	best, bestScore := models.DefaultLoanPolicy, -1
+	for _, policy := range policies {
+		if score := policy.Matches(user.Category, bookType.ItemType); score > bestScore {
+			best, bestScore = policy, score
+		}
+	}
+	return best, nil
+}
+
+// AssessFines charges the user for every closed record returned after its due date
+func AssessFines(ctx context.Context, logger *slog.Logger, store repositories.Store, records []models.Record, policy models.FinePolicy) error {
+	for _, record := range records {
+		if record.ReturnedAt == nil {
+			continue
+		}
+		amount, daysOverdue := policy.Assess(record.DueAt, *record.ReturnedAt)
+		if amount == 0 {
+			continue
+		}
+		fine := models.Fine{
+			UserID:      record.UserID,
+			RecordID:    record.ID,
+			Amount:      amount,
+			DaysOverdue: daysOverdue,
+			Status:      1,
+		}
+		if err := store.Fines().Create(&fine); err != nil {
+			return err
+		}
+		logging.Event(ctx, logger, logging.EventFineAssessed, "Fine assessed",
+			slog.Uint64("patron_id", uint64(record.UserID)), slog.Uint64("record_id", uint64(record.ID)),
+			slog.Int64("amount", amount), slog.Int("days_overdue", daysOverdue))
+	}
+	return nil
+}
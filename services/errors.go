@@ -0,0 +1,84 @@
+// Package services holds the library's business rules on top of the
+// repositories, free of HTTP and SQL.
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrCopyNotFound        = errors.New("book copy not found")
+	ErrFinesBlocked        = errors.New("outstanding fines exceed the allowed limit")
+	ErrNoCopyAvailable     = errors.New("no available copy")
+	ErrCopyTaken           = errors.New("book copy taken by another borrower")
+	ErrCopyCheckedOut      = errors.New("book copy is already checked out")
+	ErrCopyHeldForOther    = errors.New("book copy is held for another patron")
+	ErrCopyWithdrawn       = errors.New("book copy is withdrawn")
+	ErrNotCheckedOut       = errors.New("book copy is not checked out")
+	ErrLoanLimitReached    = errors.New("loan limit reached")
+	ErrRenewalLimitReached = errors.New("renewal limit reached")
+	ErrRecordNotFound      = errors.New("record not found")
+	ErrNotOwner            = errors.New("record belongs to another user")
+	ErrRecordClosed        = errors.New("record is already closed")
+	ErrOverdue             = errors.New("record is overdue")
+	ErrOpenLoans           = errors.New("user still has books on loan")
+
+	ErrBookNotFound          = errors.New("book type not found")
+	ErrBookAvailable         = errors.New("book type has a copy on the shelf")
+	ErrHoldExists            = errors.New("book type already held by the user")
+	ErrHoldLimitReached      = errors.New("hold limit reached")
+	ErrHoldNotFound          = errors.New("hold not found")
+	ErrHoldNotOwned          = errors.New("hold belongs to another user")
+	ErrHoldClosed            = errors.New("hold is already closed")
+	ErrFineNotFound          = errors.New("fine not found")
+	ErrFineSettled           = errors.New("fine is already settled")
+	ErrPaymentExceedsBalance = errors.New("payment exceeds outstanding balance")
+	ErrCopiesOnLoan          = errors.New("book type has copies on loan")
+	ErrBarcodeTaken          = errors.New("barcode already in use")
+	ErrCopyOnHoldShelf       = errors.New("book copy is on the hold shelf")
+	ErrPolicyExists          = errors.New("loan policy already exists for the scope")
+	ErrPolicyNotFound        = errors.New("loan policy not found")
+	ErrNotInTrash            = errors.New("row is not deleted")
+	ErrParentDeleted         = errors.New("parent row is deleted")
+)
+
+// RuleError carries the row a rule failed on alongside the rule's error
+type RuleError struct {
+	Err        error
+	BookTypeID uint
+	RecordID   uint
+	HoldID     uint
+	FineID     uint
+	Balance    int64
+}
+
+func (e *RuleError) Error() string {
+	switch {
+	case e.RecordID != 0:
+		return fmt.Sprintf("%v: record %d", e.Err, e.RecordID)
+	case e.HoldID != 0:
+		return fmt.Sprintf("%v: hold %d", e.Err, e.HoldID)
+	case e.FineID != 0:
+		return fmt.Sprintf("%v: fine %d", e.Err, e.FineID)
+	case e.BookTypeID != 0:
+		return fmt.Sprintf("%v: book type %d", e.Err, e.BookTypeID)
+	case e.Balance != 0:
+		return fmt.Sprintf("%v: balance %d", e.Err, e.Balance)
+	}
+	return e.Err.Error()
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// Detail returns the RuleError behind err, or an empty one
+func Detail(err error) RuleError {
+	var ruleError *RuleError
+	if errors.As(err, &ruleError) {
+		return *ruleError
+	}
+	return RuleError{Err: err}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"library/models"
+	"library/repositories"
+)
+
+// PayFines records a payment received by a member of staff, settling the
+// patron's oldest charges first, and returns it with the balance left. When
+// another payment or waiver lands on a fine in between, the fines are read
+// again and the rest of the payment goes to what is still owed.
+func PayFines(store repositories.Store, patronID uint, amount int64, staffID uint, note string) (models.FinePayment, int64, error) {
+	remaining := amount
+	for attempt := 0; remaining > 0; attempt++ {
+		if attempt == maxClaimAttempts {
+			return models.FinePayment{}, 0, fmt.Errorf("pay fines of user %d: changed by other requests %d times", patronID, maxClaimAttempts)
+		}
+
+		fines, err := store.Fines().Outstanding(patronID)
+		if err != nil {
+			return models.FinePayment{}, 0, fmt.Errorf("fetch outstanding fines: %w", err)
+		}
+		var balance int64
+		for _, fine := range fines {
+			balance += fine.Outstanding()
+		}
+		if remaining > balance {
+			// Nothing applied so far is kept, so the whole balance is still owed
+			return models.FinePayment{}, 0, &RuleError{Err: ErrPaymentExceedsBalance, Balance: balance + amount - remaining}
+		}
+
+		for _, fine := range fines {
+			if remaining == 0 {
+				break
+			}
+			applied := fine.Outstanding()
+			if applied > remaining {
+				applied = remaining
+			}
+			paid, err := store.Fines().ApplyPayment(fine.ID, applied)
+			if err != nil {
+				return models.FinePayment{}, 0, fmt.Errorf("apply payment to fine %d: %w", fine.ID, err)
+			}
+			if !paid {
+				break
+			}
+			remaining -= applied
+		}
+	}
+
+	payment := models.FinePayment{
+		UserID:       patronID,
+		Amount:       amount,
+		ReceivedByID: staffID,
+		Note:         note,
+	}
+	if err := store.Fines().CreatePayment(&payment); err != nil {
+		return models.FinePayment{}, 0, fmt.Errorf("create fine payment: %w", err)
+	}
+	balance, err := store.Fines().OutstandingBalance(patronID)
+	if err != nil {
+		return models.FinePayment{}, 0, fmt.Errorf("fetch fine balance: %w", err)
+	}
+	return payment, balance, nil
+}
+
+// WaiveFines writes off what is left of each outstanding fine
+func WaiveFines(store repositories.Store, fineIDs []uint, staffID uint, note string) ([]models.Fine, error) {
+	fines, err := store.Fines().FindByIDs(fineIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fetch fines: %w", err)
+	}
+	found := make(map[uint]bool, len(fines))
+	for _, fine := range fines {
+		found[fine.ID] = true
+	}
+	for _, id := range fineIDs {
+		if !found[id] {
+			return nil, &RuleError{Err: ErrFineNotFound, FineID: id}
+		}
+	}
+
+	for _, fine := range fines {
+		// Only outstanding fines, judged by the row as it is when written
+		waived, err := store.Fines().Waive(fine.ID, staffID, note)
+		if err != nil {
+			return nil, fmt.Errorf("waive fine %d: %w", fine.ID, err)
+		}
+		if !waived {
+			return nil, &RuleError{Err: ErrFineSettled, FineID: fine.ID}
+		}
+	}
+	return fines, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library/models"
+	"library/repositories"
+	"log/slog"
+	"time"
+)
+
+// PlaceHolds queues the user for each title, all of them or none. Holds are
+// only for titles without a copy on the shelf, one per user and title, and
+// within the hold allowance of the user's loan policy.
+func PlaceHolds(ctx context.Context, logger *slog.Logger, store repositories.Store, userID uint, bookTypeIDs []uint, now time.Time) ([]models.Hold, error) {
+	if err := ExpireHolds(ctx, logger, store, now); err != nil {
+		return nil, fmt.Errorf("expire holds: %w", err)
+	}
+
+	active, err := store.Holds().Active(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch active holds: %w", err)
+	}
+	held := make(map[uint]bool, len(active))
+	for _, hold := range active {
+		held[hold.BookTypeID] = true
+	}
+
+	var holds []models.Hold
+	for _, bookTypeID := range bookTypeIDs {
+		bookType, err := store.Books().FindBookType(bookTypeID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, &RuleError{Err: ErrBookNotFound, BookTypeID: bookTypeID}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fetch book type: %w", err)
+		}
+
+		available, err := store.Books().CountCopiesWithStatus([]uint{bookTypeID}, 1)
+		if err != nil {
+			return nil, fmt.Errorf("count available copies: %w", err)
+		}
+		if available > 0 {
+			return nil, &RuleError{Err: ErrBookAvailable, BookTypeID: bookTypeID}
+		}
+
+		if held[bookTypeID] {
+			return nil, &RuleError{Err: ErrHoldExists, BookTypeID: bookTypeID}
+		}
+		held[bookTypeID] = true
+
+		policy, err := LoanPolicyFor(store, userID, bookTypeID)
+		if err != nil {
+			return nil, fmt.Errorf("find loan policy: %w", err)
+		}
+		if policy.MaxHolds > 0 && len(active)+len(holds)+1 > policy.MaxHolds {
+			return nil, ErrHoldLimitReached
+		}
+
+		holds = append(holds, models.Hold{
+			UserID:     userID,
+			BookTypeID: bookTypeID,
+			Status:     1,
+			BookType:   bookType,
+		})
+	}
+
+	if err := store.Holds().Create(holds); err != nil {
+		return nil, fmt.Errorf("create holds: %w", err)
+	}
+	return holds, nil
+}
+
+// CancelHolds cancels the user's active holds, passing any copy already set
+// aside for them on to the next patron in the queue
+func CancelHolds(ctx context.Context, logger *slog.Logger, store repositories.Store, userID uint, holdIDs []uint, now time.Time) ([]models.Hold, error) {
+	holds, err := store.Holds().FindByIDs(holdIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fetch holds: %w", err)
+	}
+	if missing := firstMissingHold(holdIDs, holds); missing != 0 {
+		return nil, &RuleError{Err: ErrHoldNotFound, HoldID: missing}
+	}
+	// Ensure all holds belong to the user
+	for _, hold := range holds {
+		if hold.UserID != userID {
+			return nil, &RuleError{Err: ErrHoldNotOwned, HoldID: hold.ID}
+		}
+	}
+
+	for i, hold := range holds {
+		if holds[i], err = cancelHold(store, hold); err != nil {
+			return nil, err
+		}
+		// Copies already set aside go to the next patron in the queue
+		if holds[i].Status == 2 && holds[i].BookID != nil {
+			if err := ReleaseCopy(ctx, logger, store, *holds[i].BookID, holds[i].BookTypeID, 3, now); err != nil {
+				return nil, fmt.Errorf("release copy: %w", err)
+			}
+		}
+	}
+	return holds, nil
+}
+
+// firstMissingHold finds the first of ids that is not among holds
+func firstMissingHold(ids []uint, holds []models.Hold) uint {
+	found := make(map[uint]bool, len(holds))
+	for _, hold := range holds {
+		found[hold.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return id
+		}
+	}
+	return 0
+}
+
+// cancelHold cancels an active hold and returns it as it was just before.
+// When a copy is set aside for the hold, or the hold expires, in between, it
+// is read again so the copy is released by whoever cancelled the ready hold.
+func cancelHold(store repositories.Store, hold models.Hold) (models.Hold, error) {
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		if attempt > 0 {
+			holds, err := store.Holds().FindByIDs([]uint{hold.ID})
+			if err != nil {
+				return models.Hold{}, fmt.Errorf("fetch hold: %w", err)
+			}
+			if len(holds) == 0 {
+				return models.Hold{}, fmt.Errorf("fetch hold %d: %w", hold.ID, repositories.ErrNotFound)
+			}
+			hold = holds[0]
+		}
+
+		// Ensure the hold is still active
+		if hold.Status != 1 && hold.Status != 2 {
+			return models.Hold{}, &RuleError{Err: ErrHoldClosed, HoldID: hold.ID}
+		}
+		cancelled, err := store.Holds().Cancel(hold.ID, hold.Status)
+		if err != nil {
+			return models.Hold{}, fmt.Errorf("cancel hold: %w", err)
+		}
+		if cancelled {
+			return hold, nil
+		}
+	}
+	return models.Hold{}, fmt.Errorf("cancel hold %d: changed by other requests %d times", hold.ID, maxClaimAttempts)
+}
+
+// HoldResponses adds each hold's place in its title's queue
+func HoldResponses(store repositories.Store, holds []models.Hold) ([]models.HoldResponse, error) {
+	responses := make([]models.HoldResponse, len(holds))
+	for i, hold := range holds {
+		position, err := store.Holds().QueuePosition(hold)
+		if err != nil {
+			return nil, fmt.Errorf("fetch queue position: %w", err)
+		}
+		responses[i] = hold.ToResponse(position)
+	}
+	return responses, nil
+}
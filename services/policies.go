@@ -0,0 +1,34 @@
+package services
+
+import (
+	"fmt"
+	"library/models"
+	"library/repositories"
+)
+
+// SavePolicy creates the policy, or updates it when it has an ID. Only one
+// policy may cover a patron category and item type.
+func SavePolicy(store repositories.Store, policy *models.LoanPolicy) error {
+	taken, err := store.Policies().ScopeTaken(policy.PatronCategory, policy.ItemType, policy.ID)
+	if err != nil {
+		return fmt.Errorf("check existing loan policies: %w", err)
+	}
+	if taken {
+		return ErrPolicyExists
+	}
+
+	if policy.ID == 0 {
+		if err := store.Policies().Create(policy); err != nil {
+			return fmt.Errorf("create loan policy: %w", err)
+		}
+		return nil
+	}
+	updated, err := store.Policies().Update(*policy)
+	if err != nil {
+		return fmt.Errorf("update loan policy: %w", err)
+	}
+	if !updated {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library/models"
+	"library/repositories"
+	"log/slog"
+	"time"
+)
+
+// Restore brings a deleted row of the kind back. Rows deleted together share
+// a deletion time, so children deleted at or after their parent come back
+// with it, and restored copies go to the patrons waiting for their title.
+func Restore(ctx context.Context, logger *slog.Logger, store repositories.Store, kind string, id uint, now time.Time) error {
+	err := restore(ctx, logger, store, kind, id, now)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrNotInTrash
+	}
+	return err
+}
+
+func restore(ctx context.Context, logger *slog.Logger, store repositories.Store, kind string, id uint, now time.Time) error {
+	switch kind {
+	case models.TrashUsers:
+		user, err := store.Trash().DeletedUser(id)
+		if err != nil {
+			return err
+		}
+		return store.Trash().RestoreUser(id, user.DeletedAt.Time)
+
+	case models.TrashTitles:
+		bookType, err := store.Trash().DeletedBookType(id)
+		if err != nil {
+			return err
+		}
+		books, err := store.Trash().RestoreBookType(id, bookType.DeletedAt.Time)
+		if err != nil {
+			return err
+		}
+		for _, book := range books {
+			if err := ReleaseCopy(ctx, logger, store, book.ID, book.BookTypeID, book.Status, now); err != nil {
+				return fmt.Errorf("release copy: %w", err)
+			}
+		}
+		return nil
+
+	case models.TrashCopies:
+		book, err := store.Trash().DeletedCopy(id)
+		if err != nil {
+			return err
+		}
+		if _, err := store.Books().FindBookType(book.BookTypeID); err != nil {
+			return parentError(err)
+		}
+		if err := store.Trash().RestoreCopy(id); err != nil {
+			return err
+		}
+		return ReleaseCopy(ctx, logger, store, book.ID, book.BookTypeID, book.Status, now)
+
+	case models.TrashRecords:
+		record, err := store.Trash().DeletedRecord(id)
+		if err != nil {
+			return err
+		}
+		if _, err := store.Users().FindByID(record.UserID); err != nil {
+			return parentError(err)
+		}
+		return store.Trash().RestoreRecord(id)
+	}
+	return fmt.Errorf("unknown trash type %q", kind)
+}
+
+// parentError turns a missing parent into ErrParentDeleted, so it is not
+// taken for the row itself not being deleted
+func parentError(err error) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrParentDeleted
+	}
+	return err
+}
@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"library/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(router *gin.Engine, method, path string, body any) *httptest.ResponseRecorder {
+	var reader *bytes.Buffer
+	if body != nil {
+		requestBody, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(requestBody)
+	} else {
+		reader = &bytes.Buffer{}
+	}
+	req, _ := http.NewRequest(method, path, reader)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestV1GetBooks(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := serve(router, "GET", "/api/v1/books?title=mock+book&page=0&page_size=2", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	var bookListResponse BookListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&bookListResponse))
+	require.Len(t, bookListResponse.Books, 2)
+	assert.Equal(t, "Mock Book 1", bookListResponse.Books[0].Name)
+
+	w = serve(router, "GET", "/api/v1/books?page_size=ten", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestV1GetBook(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := serve(router, "GET", "/api/v1/books/1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var bookResponse struct {
+		Book struct {
+			ID             uint   `json:"id"`
+			Name           string `json:"name"`
+			TotalCount     int    `json:"total_count"`
+			AvailableCount int    `json:"available_count"`
+		} `json:"book"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&bookResponse))
+	assert.Equal(t, uint(1), bookResponse.Book.ID)
+	assert.Equal(t, "Mock Book 1", bookResponse.Book.Name)
+	assert.Equal(t, 2, bookResponse.Book.TotalCount)
+	assert.Equal(t, 1, bookResponse.Book.AvailableCount)
+
+	w = serve(router, "GET", "/api/v1/books/99", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "BOOK_NOT_FOUND", decodeError(t, w).Error.Code)
+
+	w = serve(router, "GET", "/api/v1/books/abc", nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "INVALID_ID", decodeError(t, w).Error.Code)
+}
+
+func getBookDetail(t *testing.T, router *gin.Engine, id string) models.BookDetailResponse {
+	w := serve(router, "GET", "/api/v1/books/"+id, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var bookResponse struct {
+		Book models.BookDetailResponse `json:"book"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&bookResponse))
+	return bookResponse.Book
+}
+
+func TestV1GetBookDetail(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockHoldDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	// Copy 2 is out on the overdue loan, copy 1 was returned
+	book := getBookDetail(t, router, "1")
+	assert.Equal(t, "Mock Book 1", book.Name)
+	assert.Equal(t, "book", book.ItemType)
+	assert.Equal(t, 2, book.TotalCount)
+	assert.Equal(t, 1, book.AvailableCount)
+	require.Len(t, book.Copies, 2)
+	assert.Equal(t, models.CopyResponse{ID: 1, Barcode: "LIB00000001", Status: "Available"}, book.Copies[0])
+	assert.Equal(t, "On loan", book.Copies[1].Status)
+	require.NotNil(t, book.Copies[1].DueAt)
+	assert.WithinDuration(t, parsedOverdueAt, *book.Copies[1].DueAt, 0)
+	require.NotNil(t, book.NextDueAt)
+	assert.WithinDuration(t, parsedOverdueAt, *book.NextDueAt, 0)
+	assert.Zero(t, book.HoldQueueLength)
+
+	// Only holds still waiting are in the queue
+	require.NoError(t, db.Create(&[]models.Hold{
+		{UserID: 1, BookTypeID: 3, Status: 1},
+		{UserID: 1, BookTypeID: 3, Status: 4},
+	}).Error)
+	book = getBookDetail(t, router, "3")
+	assert.Equal(t, int64(2), book.HoldQueueLength)
+	require.NotNil(t, book.NextDueAt)
+	assert.WithinDuration(t, parsedDueAt, *book.NextDueAt, 0)
+
+	// Nothing on loan, nothing expected back
+	w := serve(router, "POST", "/record/return", map[string]any{"ids": []uint{1}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Nil(t, getBookDetail(t, router, "1").NextDueAt)
+}
+
+func TestCopyShelfLocation(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := serve(router, "POST", "/catalog/copies/move", map[string]any{"id": 1, "shelf_location": "2F Fiction A-C"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = serve(router, "POST", "/catalog/copies/add", map[string]any{"book_type_id": 1, "count": 1, "shelf_location": " Reading room "})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = serve(router, "POST", "/catalog/copies/withdraw", map[string]any{"id": 1})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	book := getBookDetail(t, router, "1")
+	require.Len(t, book.Copies, 2)
+	assert.Equal(t, uint(2), book.Copies[0].ID)
+	assert.Equal(t, models.CopyResponse{ID: 7, Barcode: "LIB00000007", Status: "Available", ShelfLocation: "Reading room"}, book.Copies[1])
+
+	var moved models.Book
+	require.NoError(t, db.Unscoped().First(&moved, 1).Error)
+	assert.Equal(t, "2F Fiction A-C", moved.ShelfLocation)
+
+	w = serve(router, "POST", "/catalog/copies/move", map[string]any{"id": 1, "shelf_location": "Basement"})
+	assert.Equal(t, "COPY_NOT_FOUND", decodeError(t, w).Error.Code)
+}
+
+func TestV1Loans(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := serve(router, "POST", "/api/v1/loans", map[string][]int{"ids": {1}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(router, "PATCH", "/api/v1/loans/3", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(router, "PATCH", "/api/v1/loans/1", nil)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "LOAN_OVERDUE", decodeError(t, w).Error.Code)
+
+	w = serve(router, "POST", "/api/v1/loans/3/return", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(router, "POST", "/api/v1/loans/0/return", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(router, "PATCH", "/api/v1/loans/999", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	errResp := decodeError(t, w)
+	assert.Equal(t, "RECORD_NOT_FOUND", errResp.Error.Code)
+	assert.Equal(t, float64(999), errResp.Error.Details["record_id"])
+
+	w = serve(router, "POST", "/api/v1/loans/999/return", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	errResp = decodeError(t, w)
+	assert.Equal(t, "RECORD_NOT_FOUND", errResp.Error.Code)
+	assert.Equal(t, float64(999), errResp.Error.Details["record_id"])
+
+	w = serve(router, "GET", "/api/v1/me/loans?status=2", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var recordListResponse RecordListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&recordListResponse))
+	assert.Equal(t, 2, recordListResponse.Total) // record 4 and the one just returned
+}
+
+func TestRPCRoutesDeprecated(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	for _, path := range []string{"/book/list", "/record/list"} {
+		w := serve(router, "POST", path, map[string]int{"page_size": 10})
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Equal(t, "@1792281600", w.Header().Get("Deprecation"), path)
+		assert.Contains(t, w.Header().Get("Link"), `rel="deprecation"`, path)
+	}
+	w := serve(router, "POST", "/record/extend", map[string][]int{"ids": {3}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Deprecation"))
+}
@@ -7,7 +7,9 @@ import (
 	"library/models"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	// Adjust package import based on your project structure
 
@@ -16,8 +18,10 @@ import (
 )
 
 type BookListResponse struct {
-	Books []models.BookResponse `json:"books"`
-	Total int                   `json:"total"`
+	Books      []models.BookResponse `json:"books"`
+	Total      int                   `json:"total"`
+	NextCursor string                `json:"next_cursor"`
+	HasMore    bool                  `json:"has_more"`
 }
 
 var mockBookListExpectredReturn = BookListResponse{
@@ -128,3 +132,145 @@ func TestBorrowBooksSuccess(t *testing.T) {
 		assert.Equal(t, mockBorrowSuccessExpectedReturn.Record[index].ID, borrowResponse.Record[index].ID)
 	}
 }
+
+func bookListIDs(t *testing.T, w *httptest.ResponseRecorder) ([]uint, int) {
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var bookListResponse BookListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&bookListResponse))
+	ids := []uint{}
+	for _, book := range bookListResponse.Books {
+		ids = append(ids, book.ID)
+	}
+	return ids, bookListResponse.Total
+}
+
+func TestGetBookListSortAndFilter(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	// Titles: 1 Zebra, 2 Mock Book 2 (added last), 3 apple (borrowed most)
+	require.NoError(t, db.Model(&models.BookType{}).Where("id = ?", 1).Update("title", "Zebra").Error)
+	require.NoError(t, db.Model(&models.BookType{}).Where("id = ?", 3).Update("title", "apple").Error)
+	require.NoError(t, db.Model(&models.BookType{}).Where("id = ?", 2).Update("created_at", time.Now().Add(time.Hour)).Error)
+	require.NoError(t, db.Create(&[]models.Record{
+		{UserID: 2, BookID: 6, IsClosed: true},
+		{UserID: 2, BookID: 6, IsClosed: true},
+	}).Error)
+
+	tests := []struct {
+		query string
+		ids   []uint
+		total int
+	}{
+		{"", []uint{1, 2, 3}, 3},
+		{"sort=title", []uint{3, 2, 1}, 3},
+		{"sort=newest", []uint{2, 3, 1}, 3},
+		{"sort=popular", []uint{3, 1, 2}, 3},
+		{"sort=available", []uint{2, 1, 3}, 3},
+		{"sort=available&page=1&page_size=2", []uint{3}, 3},
+		{"available=true", []uint{1, 2}, 2},
+		{"available=true&sort=available", []uint{2, 1}, 2},
+		{"title=zebra", []uint{1}, 1},
+		{"title=zebra&page=1&page_size=1", []uint{}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query := tt.query
+			if !strings.Contains(query, "page_size") {
+				query += "&page_size=10"
+			}
+			ids, total := bookListIDs(t, serve(router, "GET", "/api/v1/books?"+query, nil))
+			assert.Equal(t, tt.ids, ids)
+			assert.Equal(t, tt.total, total)
+		})
+	}
+
+	// The deprecated POST route takes the same options in its body
+	ids, total := bookListIDs(t, serve(router, "POST", "/book/list", map[string]any{"sort": "popular", "available": true, "page_size": 10}))
+	assert.Equal(t, []uint{1, 2}, ids)
+	assert.Equal(t, 2, total)
+
+	w := serve(router, "GET", "/api/v1/books?sort=rating", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestGetBookListCursor(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	// Titles 4 to 9 share their arrival time, titles, borrows and shelf
+	// counts in pairs so every order has ties for the id to break
+	arrived := time.Now().Add(-time.Hour)
+	for _, title := range []string{"Beta", "alpha", "Beta", "Gamma", "alpha", "Delta"} {
+		require.NoError(t, db.Create(&models.BookType{Title: title, CommonTime: models.CommonTime{CreatedAt: arrived}}).Error)
+	}
+	require.NoError(t, db.Create(&[]models.Book{{BookTypeID: 4, Status: 1}, {BookTypeID: 6, Status: 1}, {BookTypeID: 7, Status: 1}}).Error)
+
+	for _, sortOrder := range []string{"id", "title", "newest", "popular", "available"} {
+		t.Run(sortOrder, func(t *testing.T) {
+			w := serve(router, "GET", "/api/v1/books?page_size=100&sort="+sortOrder, nil)
+			all, total := bookListIDs(t, w)
+			require.Equal(t, 9, total)
+			require.Len(t, all, 9)
+
+			var walked []uint
+			query := "/api/v1/books?page_size=2&sort=" + sortOrder
+			for pages := 0; pages < 10; pages++ {
+				w := serve(router, "GET", query, nil)
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				var bookListResponse BookListResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&bookListResponse))
+				assert.Equal(t, 9, bookListResponse.Total)
+				for _, book := range bookListResponse.Books {
+					walked = append(walked, book.ID)
+				}
+				if !bookListResponse.HasMore {
+					assert.Empty(t, bookListResponse.NextCursor)
+					break
+				}
+				query = "/api/v1/books?page_size=2&sort=" + sortOrder + "&cursor=" + bookListResponse.NextCursor
+			}
+			assert.Equal(t, all, walked)
+		})
+	}
+
+	// The cursor keeps the filters of the request it is sent with
+	w := serve(router, "GET", "/api/v1/books?page_size=1&title=alpha", nil)
+	var bookListResponse BookListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&bookListResponse))
+	require.True(t, bookListResponse.HasMore)
+	ids, total := bookListIDs(t, serve(router, "GET", "/api/v1/books?page_size=1&title=alpha&cursor="+bookListResponse.NextCursor, nil))
+	assert.Equal(t, []uint{8}, ids)
+	assert.Equal(t, 2, total)
+
+	// A cursor is only good for the order it was issued for
+	w = serve(router, "GET", "/api/v1/books?sort=title&cursor="+bookListResponse.NextCursor, nil)
+	assert.Equal(t, "INVALID_CURSOR", decodeError(t, w).Error.Code)
+	w = serve(router, "GET", "/api/v1/books?cursor=not-a-cursor", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "INVALID_CURSOR", decodeError(t, w).Error.Code)
+}
+
+func TestGetBookListPageSizeLimit(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	bookTypes := make([]models.BookType, models.MaxPageSize+10)
+	for i := range bookTypes {
+		bookTypes[i].Title = "Extra"
+	}
+	require.NoError(t, db.CreateInBatches(&bookTypes, 50).Error)
+
+	ids, total := bookListIDs(t, serve(router, "GET", "/api/v1/books", nil))
+	assert.Len(t, ids, models.DefaultPageSize)
+	assert.Equal(t, models.MaxPageSize+13, total)
+
+	ids, _ = bookListIDs(t, serve(router, "GET", "/api/v1/books?page_size=1000", nil))
+	assert.Len(t, ids, models.MaxPageSize)
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"library/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBookTypeSuccess(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"title":  "New Book",
+		"copies": 2,
+	})
+	req, _ := http.NewRequest("POST", "/catalog/create", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var available int64
+	require.NoError(t, db.Model(&models.Book{}).Where("book_type_id = ? AND status = 1", 4).Count(&available).Error)
+	assert.Equal(t, int64(2), available)
+}
+
+func TestDeleteBookTypesRentedOut(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{1}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/catalog/delete", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestWithdrawCopyRentedOut(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]int{
+		"id": 2,
+	})
+	req, _ := http.NewRequest("POST", "/catalog/copies/withdraw", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestWithdrawCopySuccess(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]int{
+		"id": 1,
+	})
+	req, _ := http.NewRequest("POST", "/catalog/copies/withdraw", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Withdrawn copies are soft deleted
+	var book models.Book
+	require.NoError(t, db.Unscoped().First(&book, 1).Error)
+	assert.Equal(t, uint(4), book.Status)
+	assert.True(t, book.DeletedAt.Valid)
+}
+
+func TestCreateBookTypeInvalidISBN(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"title":  "New Book",
+		"isbn13": "978-0-306-40615-8",
+	})
+	req, _ := http.NewRequest("POST", "/catalog/create", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestGetBookListFilterByAuthor(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"title":            "Experiments in Physics",
+		"authors":          []string{"Ada Writer"},
+		"isbn10":           "0-306-40615-2",
+		"publication_year": 1999,
+		"language":         "EN",
+		"subjects":         []string{"Physics"},
+		"copies":           1,
+	})
+	req, _ := http.NewRequest("POST", "/catalog/create", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	requestBody, _ = json.Marshal(map[string]interface{}{
+		"author":    "writer",
+		"page_size": 10,
+		"page":      0,
+	})
+	req, _ = http.NewRequest("POST", "/book/list", bytes.NewBuffer(requestBody))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var bookListResponse BookListResponse
+	err := json.NewDecoder(w.Body).Decode(&bookListResponse)
+	require.NoError(t, err) // Ensure JSON decoding is successful
+
+	require.Len(t, bookListResponse.Books, 1)
+	book := bookListResponse.Books[0]
+	assert.Equal(t, "Experiments in Physics", book.Name)
+	assert.Equal(t, []string{"Ada Writer"}, book.Authors)
+	assert.Equal(t, "9780306406157", book.ISBN13)
+	assert.Equal(t, "en", book.Language)
+	assert.Equal(t, []string{"Physics"}, book.Subjects)
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"library/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOutByBarcodeSuccess(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"barcode": "LIB00000003",
+		"user_id": 2,
+	})
+	req, _ := http.NewRequest("POST", "/circulation/checkout", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var book models.Book
+	require.NoError(t, db.First(&book, 3).Error)
+	assert.Equal(t, uint(2), book.Status)
+
+	var record models.Record
+	require.NoError(t, db.Where("book_id = ? AND is_closed = ?", 3, false).First(&record).Error)
+	assert.Equal(t, uint(2), record.UserID)
+}
+
+func TestCheckOutByBarcodeRentedOut(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"barcode": "LIB00000002",
+		"user_id": 2,
+	})
+	req, _ := http.NewRequest("POST", "/circulation/checkout", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCheckInByBarcodeSuccess(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"barcode": "LIB00000006",
+	})
+	req, _ := http.NewRequest("POST", "/circulation/checkin", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var record models.Record
+	require.NoError(t, db.First(&record, 3).Error)
+	assert.True(t, record.IsClosed)
+
+	var book models.Book
+	require.NoError(t, db.First(&book, 6).Error)
+	assert.Equal(t, uint(1), book.Status)
+}
+
+func TestCheckInByBarcodeNotCheckedOut(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"barcode": "LIB00000001",
+	})
+	req, _ := http.NewRequest("POST", "/circulation/checkin", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,443 @@
+package tests
+
+import (
+	"context"
+	"library/logging"
+	"library/models"
+	"library/repositories"
+	"library/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var serviceNow = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// newServiceFixture sets up one patron, two titles with two copies each and
+// a service that reads the clock and fine policy from the fixture
+func newServiceFixture() (*fakeStore, *services.CirculationService) {
+	store := newFakeStore()
+	store.users[1] = models.User{ID: 1, Category: "standard"}
+	store.users[2] = models.User{ID: 2, Category: "standard"}
+	store.types[1] = models.BookType{ID: 1, Title: "Title 1", ItemType: "book"}
+	store.types[2] = models.BookType{ID: 2, Title: "Title 2", ItemType: "book"}
+	for id := uint(1); id <= 4; id++ {
+		barcode := "LIB-" + string(rune('0'+id))
+		store.books[id] = models.Book{ID: id, BookTypeID: (id + 1) / 2, Barcode: &barcode, Status: 1}
+	}
+
+	service := &services.CirculationService{
+		Store:      store,
+		FinePolicy: testConfig.Fines.Policy(),
+		Now:        func() time.Time { return serviceNow },
+		Log:        logging.Discard(),
+	}
+	return store, service
+}
+
+func TestServiceBorrowTakesShelfCopy(t *testing.T) {
+	store, service := newServiceFixture()
+
+	records, err := service.Borrow(context.Background(), 1, []uint{1})
+
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, uint(1), records[0].BookID)
+	assert.Equal(t, serviceNow.AddDate(0, 0, models.DefaultLoanPolicy.LoanDays), records[0].DueAt)
+	assert.Equal(t, uint(2), store.books[1].Status)
+}
+
+func TestServiceBorrowPrefersHeldCopy(t *testing.T) {
+	store, service := newServiceFixture()
+	heldID := uint(2)
+	expiresAt := serviceNow.AddDate(0, 0, 1)
+	store.books[2] = models.Book{ID: 2, BookTypeID: 1, Status: 3}
+	store.holds[1] = models.Hold{ID: 1, UserID: 1, BookTypeID: 1, BookID: &heldID, Status: 2, ExpiresAt: &expiresAt}
+
+	records, err := service.Borrow(context.Background(), 1, []uint{1})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), records[0].BookID)
+	assert.Equal(t, uint(1), store.books[1].Status)
+	assert.Equal(t, uint(3), store.holds[1].Status)
+}
+
+func TestServiceBorrowNoCopyAvailable(t *testing.T) {
+	_, service := newServiceFixture()
+
+	_, err := service.Borrow(context.Background(), 1, []uint{1, 1, 1})
+
+	assert.ErrorIs(t, err, services.ErrNoCopyAvailable)
+	assert.Equal(t, uint(1), services.Detail(err).BookTypeID)
+}
+
+func TestServiceBorrowLoanLimit(t *testing.T) {
+	store, service := newServiceFixture()
+	store.policies = []models.LoanPolicy{{PatronCategory: "standard", LoanDays: 14, MaxLoans: 1}}
+
+	_, err := service.Borrow(context.Background(), 1, []uint{1, 2})
+
+	assert.ErrorIs(t, err, services.ErrLoanLimitReached)
+}
+
+func TestServiceBorrowBlockedByFines(t *testing.T) {
+	store, service := newServiceFixture()
+	store.fines = []models.Fine{{UserID: 1, Amount: 1500, Status: 1}}
+
+	_, err := service.Borrow(context.Background(), 1, []uint{1})
+
+	assert.ErrorIs(t, err, services.ErrFinesBlocked)
+	assert.Equal(t, int64(1500), services.Detail(err).Balance)
+	assert.Equal(t, uint(1), store.books[1].Status)
+}
+
+func TestServiceRenewExtendsDueDate(t *testing.T) {
+	store, service := newServiceFixture()
+	dueAt := serviceNow.AddDate(0, 0, 3)
+	store.records[1] = models.Record{ID: 1, UserID: 1, BookID: 1, DueAt: dueAt}
+
+	records, err := service.Renew(context.Background(), 1, []uint{1})
+
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, dueAt.AddDate(0, 0, models.DefaultLoanPolicy.RenewalDays), store.records[1].DueAt)
+	assert.Equal(t, 1, store.records[1].RenewalCount)
+}
+
+func TestServiceRenewRefusals(t *testing.T) {
+	store, service := newServiceFixture()
+	store.records[1] = models.Record{ID: 1, UserID: 2, BookID: 1, DueAt: serviceNow.AddDate(0, 0, 3)}
+	store.records[2] = models.Record{ID: 2, UserID: 1, BookID: 2, DueAt: serviceNow.AddDate(0, 0, -1)}
+	store.records[3] = models.Record{ID: 3, UserID: 1, BookID: 3, DueAt: serviceNow.AddDate(0, 0, 3), RenewalCount: models.DefaultLoanPolicy.MaxRenewals}
+	store.records[4] = models.Record{ID: 4, UserID: 1, BookID: 4, DueAt: serviceNow.AddDate(0, 0, 3), IsClosed: true}
+
+	_, err := service.Renew(context.Background(), 1, []uint{1})
+	assert.ErrorIs(t, err, services.ErrNotOwner)
+
+	_, err = service.Renew(context.Background(), 1, []uint{2})
+	assert.ErrorIs(t, err, services.ErrOverdue)
+
+	_, err = service.Renew(context.Background(), 1, []uint{3})
+	assert.ErrorIs(t, err, services.ErrRenewalLimitReached)
+	assert.Equal(t, uint(3), services.Detail(err).RecordID)
+
+	_, err = service.Renew(context.Background(), 1, []uint{4})
+	assert.ErrorIs(t, err, services.ErrRecordClosed)
+}
+
+func TestServiceReturnFinesAndPassesToHold(t *testing.T) {
+	store, service := newServiceFixture()
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 2}
+	store.records[1] = models.Record{ID: 1, UserID: 1, BookID: 1, DueAt: serviceNow.AddDate(0, 0, -4)}
+	store.holds[1] = models.Hold{ID: 1, UserID: 2, BookTypeID: 1, Status: 1}
+
+	records, err := service.Return(context.Background(), 1, []uint{1})
+
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.True(t, store.records[1].IsClosed)
+	require.Len(t, store.fines, 1)
+	assert.Equal(t, int64(100), store.fines[0].Amount)
+	assert.Equal(t, 4, store.fines[0].DaysOverdue)
+	assert.Equal(t, uint(3), store.books[1].Status)
+	assert.Equal(t, uint(2), store.holds[1].Status)
+	require.NotNil(t, store.holds[1].BookID)
+	assert.Equal(t, uint(1), *store.holds[1].BookID)
+}
+
+func TestServiceReturnOtherUserRecord(t *testing.T) {
+	store, service := newServiceFixture()
+	store.records[1] = models.Record{ID: 1, UserID: 2, BookID: 1, DueAt: serviceNow.AddDate(0, 0, 3)}
+
+	_, err := service.Return(context.Background(), 1, []uint{1})
+
+	assert.ErrorIs(t, err, services.ErrNotOwner)
+	assert.False(t, store.records[1].IsClosed)
+}
+
+func TestServiceCheckOutHeldForOther(t *testing.T) {
+	store, service := newServiceFixture()
+	heldID := uint(1)
+	expiresAt := serviceNow.AddDate(0, 0, 1)
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Barcode: store.books[1].Barcode, Status: 3}
+	store.holds[1] = models.Hold{ID: 1, UserID: 2, BookTypeID: 1, BookID: &heldID, Status: 2, ExpiresAt: &expiresAt}
+
+	_, err := service.CheckOut(context.Background(), 1, *store.books[1].Barcode)
+	assert.ErrorIs(t, err, services.ErrCopyHeldForOther)
+
+	record, err := service.CheckOut(context.Background(), 2, *store.books[1].Barcode)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), record.UserID)
+}
+
+// staleStore answers the first reads of copies, records and holds with a snapshot
+// taken before a concurrent request changed them, as a transaction racing it would
+type staleStore struct {
+	*fakeStore
+	snapshot     map[uint]models.Record
+	holdSnapshot map[uint]models.Hold
+	bookSnapshot map[uint]models.Book
+	staleReads   *int
+}
+
+func newStaleStore(store *fakeStore, staleReads int) staleStore {
+	snapshot := make(map[uint]models.Record, len(store.records))
+	for id, record := range store.records {
+		snapshot[id] = record
+	}
+	holdSnapshot := make(map[uint]models.Hold, len(store.holds))
+	for id, hold := range store.holds {
+		holdSnapshot[id] = hold
+	}
+	bookSnapshot := make(map[uint]models.Book, len(store.books))
+	for id, book := range store.books {
+		bookSnapshot[id] = book
+	}
+	return staleStore{fakeStore: store, snapshot: snapshot, holdSnapshot: holdSnapshot, bookSnapshot: bookSnapshot, staleReads: &staleReads}
+}
+
+func (s staleStore) Books() repositories.BookRepository {
+	return staleBooks{fakeBooks: fakeBooks{s: s.fakeStore}, store: s}
+}
+
+func (s staleStore) Records() repositories.RecordRepository {
+	return staleRecords{fakeRecords: fakeRecords{s: s.fakeStore}, store: s}
+}
+
+func (s staleStore) Holds() repositories.HoldRepository {
+	return staleHolds{fakeHolds: fakeHolds{s: s.fakeStore}, store: s}
+}
+
+func (s staleStore) Transaction(fn func(repositories.Store) error) error { return fn(s) }
+
+type staleRecords struct {
+	fakeRecords
+	store staleStore
+}
+
+func (r staleRecords) FindByIDs(ids []uint) ([]models.Record, error) {
+	if *r.store.staleReads == 0 {
+		return r.fakeRecords.FindByIDs(ids)
+	}
+	*r.store.staleReads--
+	var records []models.Record
+	for _, id := range ids {
+		if record, ok := r.store.snapshot[id]; ok {
+			record.Book = r.s.books[record.BookID]
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+type staleBooks struct {
+	fakeBooks
+	store staleStore
+}
+
+func (r staleBooks) FirstCopyWithStatus(bookTypeID, status uint) (models.Book, error) {
+	if *r.store.staleReads == 0 {
+		return r.fakeBooks.FirstCopyWithStatus(bookTypeID, status)
+	}
+	*r.store.staleReads--
+	return fakeBooks{s: &fakeStore{books: r.store.bookSnapshot}}.FirstCopyWithStatus(bookTypeID, status)
+}
+
+type staleHolds struct {
+	fakeHolds
+	store staleStore
+}
+
+func (r staleHolds) FindByIDs(ids []uint) ([]models.Hold, error) {
+	if *r.store.staleReads == 0 {
+		return r.fakeHolds.FindByIDs(ids)
+	}
+	*r.store.staleReads--
+	var holds []models.Hold
+	for _, id := range ids {
+		if hold, ok := r.store.holdSnapshot[id]; ok {
+			holds = append(holds, hold)
+		}
+	}
+	return holds, nil
+}
+
+func (r staleHolds) FirstWaiting(bookTypeID uint) (models.Hold, error) {
+	if *r.store.staleReads == 0 {
+		return r.fakeHolds.FirstWaiting(bookTypeID)
+	}
+	*r.store.staleReads--
+	return fakeHolds{s: &fakeStore{holds: r.store.holdSnapshot}}.FirstWaiting(bookTypeID)
+}
+
+func (r staleHolds) ExpiredReady(now time.Time) ([]models.Hold, error) {
+	if *r.store.staleReads == 0 {
+		return r.fakeHolds.ExpiredReady(now)
+	}
+	*r.store.staleReads--
+	return fakeHolds{s: &fakeStore{holds: r.store.holdSnapshot}}.ExpiredReady(now)
+}
+
+func TestServiceBorrowCopyTakenConcurrently(t *testing.T) {
+	store, service := newServiceFixture()
+	service.Store = newStaleStore(store, 1)
+
+	// Another borrower takes the first copy after this one picked it
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 2}
+
+	records, err := service.Borrow(context.Background(), 1, []uint{1})
+
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, uint(2), records[0].BookID)
+	assert.Equal(t, uint(2), store.books[2].Status)
+}
+
+func TestServiceCancelHoldMadeReadyConcurrently(t *testing.T) {
+	store, _ := newServiceFixture()
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 2}
+	store.holds[1] = models.Hold{ID: 1, UserID: 1, BookTypeID: 1, Status: 1}
+	store.holds[2] = models.Hold{ID: 2, UserID: 2, BookTypeID: 1, Status: 1}
+	stale := newStaleStore(store, 1)
+
+	// The copy comes back and is set aside for the hold after it was read
+	require.NoError(t, services.ReleaseCopy(context.Background(), logging.Discard(), store, 1, 1, 2, serviceNow))
+	require.Equal(t, uint(2), store.holds[1].Status)
+
+	holds, err := services.CancelHolds(context.Background(), logging.Discard(), stale, 1, []uint{1}, serviceNow)
+
+	require.NoError(t, err)
+	require.Len(t, holds, 1)
+	assert.Equal(t, uint(4), store.holds[1].Status)
+	// The copy goes on to the next patron instead of staying on the hold shelf
+	assert.Equal(t, uint(2), store.holds[2].Status)
+	assert.Equal(t, uint(1), *store.holds[2].BookID)
+	assert.Equal(t, uint(3), store.books[1].Status)
+}
+
+func TestServiceCancelHoldExpiredConcurrently(t *testing.T) {
+	store, _ := newServiceFixture()
+	bookID := uint(1)
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 3}
+	store.holds[1] = models.Hold{ID: 1, UserID: 1, BookTypeID: 1, BookID: &bookID, Status: 2}
+	stale := newStaleStore(store, 1)
+
+	// The hold expires, and its copy goes back on the shelf, after it was read
+	store.holds[1] = models.Hold{ID: 1, UserID: 1, BookTypeID: 1, BookID: &bookID, Status: 5}
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 1}
+
+	_, err := services.CancelHolds(context.Background(), logging.Discard(), stale, 1, []uint{1}, serviceNow)
+
+	assert.ErrorIs(t, err, services.ErrHoldClosed)
+	assert.Equal(t, uint(1), services.Detail(err).HoldID)
+	assert.Equal(t, uint(5), store.holds[1].Status)
+	assert.Equal(t, uint(1), store.books[1].Status)
+}
+
+func TestServiceReturnClosedConcurrently(t *testing.T) {
+	store, service := newServiceFixture()
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 2}
+	store.records[1] = models.Record{ID: 1, UserID: 1, BookID: 1, DueAt: serviceNow.AddDate(0, 0, -4)}
+	service.Store = newStaleStore(store, 1)
+
+	// Another return closes the record after this one read it
+	returnedAt := serviceNow.Add(-time.Minute)
+	record := store.records[1]
+	record.IsClosed, record.ReturnedAt = true, &returnedAt
+	store.records[1] = record
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 1}
+
+	_, err := service.Return(context.Background(), 1, []uint{1})
+
+	assert.ErrorIs(t, err, services.ErrRecordClosed)
+	assert.Equal(t, uint(1), services.Detail(err).RecordID)
+	assert.Empty(t, store.fines)
+	assert.Equal(t, &returnedAt, store.records[1].ReturnedAt)
+}
+
+func TestServiceRenewRenewedConcurrently(t *testing.T) {
+	store, service := newServiceFixture()
+	dueAt := serviceNow.AddDate(0, 0, 3)
+	store.records[1] = models.Record{ID: 1, UserID: 1, BookID: 1, DueAt: dueAt}
+	store.records[2] = models.Record{ID: 2, UserID: 1, BookID: 3, DueAt: dueAt, RenewalCount: models.DefaultLoanPolicy.MaxRenewals - 1}
+	service.Store = newStaleStore(store, 1)
+
+	// Another renewal of each lands after this one read them
+	renewalDays := models.DefaultLoanPolicy.RenewalDays
+	for _, id := range []uint{1, 2} {
+		record := store.records[id]
+		record.DueAt = record.DueAt.AddDate(0, 0, renewalDays)
+		record.RenewalCount++
+		store.records[id] = record
+	}
+
+	// The second renewal builds on the first
+	records, err := service.Renew(context.Background(), 1, []uint{1})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, dueAt.AddDate(0, 0, 2*renewalDays), store.records[1].DueAt)
+	assert.Equal(t, 2, store.records[1].RenewalCount)
+
+	// and cannot go past the limit
+	*service.Store.(staleStore).staleReads = 1
+	_, err = service.Renew(context.Background(), 1, []uint{2})
+	assert.ErrorIs(t, err, services.ErrRenewalLimitReached)
+	assert.Equal(t, models.DefaultLoanPolicy.MaxRenewals, store.records[2].RenewalCount)
+	assert.Equal(t, dueAt.AddDate(0, 0, renewalDays), store.records[2].DueAt)
+}
+
+func TestServiceExpireHoldCancelledConcurrently(t *testing.T) {
+	store, _ := newServiceFixture()
+	bookID := uint(1)
+	expiresAt := serviceNow.Add(-time.Hour)
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 3}
+	store.holds[1] = models.Hold{ID: 1, UserID: 1, BookTypeID: 1, BookID: &bookID, Status: 2, ExpiresAt: &expiresAt}
+	store.holds[2] = models.Hold{ID: 2, UserID: 2, BookTypeID: 1, Status: 1}
+	store.holds[3] = models.Hold{ID: 3, UserID: 3, BookTypeID: 1, Status: 1}
+	stale := newStaleStore(store, 1)
+
+	// The patron cancels the hold after it was read, passing the copy on
+	store.holds[1] = models.Hold{ID: 1, UserID: 1, BookTypeID: 1, BookID: &bookID, Status: 4, ExpiresAt: &expiresAt}
+	require.NoError(t, services.ReleaseCopy(context.Background(), logging.Discard(), store, 1, 1, 3, serviceNow))
+	require.Equal(t, uint(2), store.holds[2].Status)
+
+	require.NoError(t, services.ExpireHolds(context.Background(), logging.Discard(), stale, serviceNow))
+
+	// The copy stays with the next patron instead of being released twice
+	assert.Equal(t, uint(4), store.holds[1].Status)
+	assert.Equal(t, uint(2), store.holds[2].Status)
+	assert.Equal(t, uint(1), store.holds[3].Status)
+	assert.Equal(t, uint(3), store.books[1].Status)
+}
+
+func TestServiceReleaseCopyHoldTakenConcurrently(t *testing.T) {
+	store, _ := newServiceFixture()
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 2}
+	store.holds[1] = models.Hold{ID: 1, UserID: 1, BookTypeID: 1, Status: 1}
+	store.holds[2] = models.Hold{ID: 2, UserID: 2, BookTypeID: 1, Status: 1}
+	stale := newStaleStore(store, 1)
+
+	// The first hold in the queue is cancelled after it was read
+	store.holds[1] = models.Hold{ID: 1, UserID: 1, BookTypeID: 1, Status: 4}
+
+	require.NoError(t, services.ReleaseCopy(context.Background(), logging.Discard(), stale, 1, 1, 2, serviceNow))
+
+	assert.Equal(t, uint(4), store.holds[1].Status)
+	assert.Nil(t, store.holds[1].BookID)
+	assert.Equal(t, uint(2), store.holds[2].Status)
+	assert.Equal(t, uint(1), *store.holds[2].BookID)
+	assert.Equal(t, uint(3), store.books[1].Status)
+}
+
+func TestServiceReleaseCopyMovedConcurrently(t *testing.T) {
+	store, _ := newServiceFixture()
+	// Another request already put the copy back on the shelf
+	store.books[1] = models.Book{ID: 1, BookTypeID: 1, Status: 1}
+
+	err := services.ReleaseCopy(context.Background(), logging.Discard(), store, 1, 1, 2, serviceNow)
+
+	assert.ErrorIs(t, err, services.ErrCopyTaken)
+	assert.Equal(t, uint(1), store.books[1].Status)
+}
@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"library/models"
+	"library/repositories"
+	"library/services"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// requireParallelDB skips a race test on the SQLite test database: it has a
+// single connection, so the racing requests take turns and the test would
+// pass whether or not the code guards against the race. CI runs them in its
+// postgres job (.github/workflows/test.yml); the service tests with a stale
+// store cover the retry logic, and TestDoubleClaimRefusedByOpenRecordIndex
+// the open record index, on every engine.
+func requireParallelDB(t *testing.T, db *gorm.DB) {
+	if db.Dialector.Name() != "postgres" {
+		t.Skip("SQLite serializes the requests; set TEST_DB_DRIVER=postgres to run the race")
+	}
+}
+
+// Many patrons race for the only available copy of Mock Book 1
+func TestBorrowBooksConcurrentLastCopy(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	requireParallelDB(t, db)
+	PrepareMockRecordDB(db)
+	router := SetupMockRouter(db)
+
+	const borrowers = 20
+	codes := make(chan int, borrowers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < borrowers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requestBody, _ := json.Marshal(map[string][]int{
+				"ids": {1},
+			})
+			req, _ := http.NewRequest("POST", "/book/borrow", bytes.NewBuffer(requestBody))
+			w := httptest.NewRecorder()
+			<-start
+			router.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+
+	succeeded := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+			continue
+		}
+		assert.Contains(t, []int{http.StatusNotFound, http.StatusConflict}, code)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	var openRecords int64
+	require.NoError(t, db.Model(&models.Record{}).Where("book_id = ? AND is_closed = ?", 1, false).Count(&openRecords).Error)
+	assert.Equal(t, int64(1), openRecords)
+}
+
+// The same overdue loan returned twice at once is closed, fined and handed
+// to the waiting hold only once
+func TestReturnRecordConcurrently(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	requireParallelDB(t, db)
+	PrepareMockRecordDB(db)
+	router := SetupMockRouter(db)
+	require.NoError(t, db.Omit("User", "BookType").Create(&models.Hold{UserID: 2, BookTypeID: 1, Status: 1}).Error)
+
+	const returns = 2
+	responses := make(chan *httptest.ResponseRecorder, returns)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < returns; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			responses <- serve(router, "POST", "/record/return", map[string][]int{"ids": {1}})
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(responses)
+
+	succeeded := 0
+	for w := range responses {
+		if w.Code == http.StatusOK {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, "RECORD_CLOSED", decodeError(t, w).Error.Code)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	var fines int64
+	require.NoError(t, db.Model(&models.Fine{}).Where("record_id = ?", 1).Count(&fines).Error)
+	assert.Equal(t, int64(1), fines)
+
+	// A second release would have found nobody waiting and reshelved the copy
+	var book models.Book
+	require.NoError(t, db.First(&book, 2).Error)
+	assert.Equal(t, uint(3), book.Status)
+}
+
+// The database itself refuses a second open record for the same copy
+func TestOpenRecordUniquePerBook(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+
+	record := models.Record{
+		UserID: 1,
+		BookID: 2, // already lent out by the first mock record
+		DueAt:  time.Now().AddDate(0, 0, 28),
+	}
+	assert.Error(t, db.Omit("User", "Book").Create(&record).Error)
+}
+
+// Two checkouts of one copy that both got past the copy's status, one after
+// the other, still leave a single open record: the index refuses the second
+// on every engine, so this runs on SQLite too
+func TestDoubleClaimRefusedByOpenRecordIndex(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	store := repositories.NewStore(db)
+
+	claim := func() error {
+		return store.Records().Create([]models.Record{{
+			UserID: 1,
+			BookID: 2, // already lent out by the first mock record
+			DueAt:  time.Now().AddDate(0, 0, 28),
+		}})
+	}
+	assert.ErrorIs(t, claim(), repositories.ErrDuplicate)
+
+	// Once that loan is returned the copy can be lent again
+	require.NoError(t, db.Model(&models.Record{}).Where("book_id = ?", 2).Update("is_closed", true).Error)
+	require.NoError(t, claim())
+	assert.ErrorIs(t, claim(), repositories.ErrDuplicate)
+}
+
+// lendBeforeDelete lends a copy right before the titles are deleted, as a
+// checkout committing between the count of copies on loan and the delete would
+type lendBeforeDelete struct {
+	repositories.Store
+	bookID uint
+}
+
+func (s lendBeforeDelete) Books() repositories.BookRepository {
+	return lendBeforeDeleteBooks{BookRepository: s.Store.Books(), bookID: s.bookID}
+}
+
+type lendBeforeDeleteBooks struct {
+	repositories.BookRepository
+	bookID uint
+}
+
+func (r lendBeforeDeleteBooks) DeleteBookTypes(ids []uint, deletedAt time.Time) (int64, error) {
+	if _, err := r.ClaimCopy(r.bookID, 1); err != nil {
+		return 0, err
+	}
+	return r.BookRepository.DeleteBookTypes(ids, deletedAt)
+}
+
+func TestDeleteBookTypesCopyLentConcurrently(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+
+	// Mock Book 2 starts with every copy on the shelf
+	require.NoError(t, db.Model(&models.Book{}).Where("id = ?", 5).Update("status", 1).Error)
+
+	err := repositories.NewStore(db).Transaction(func(store repositories.Store) error {
+		return services.DeleteBookTypes(lendBeforeDelete{Store: store, bookID: 3}, []uint{2}, time.Now())
+	})
+
+	assert.ErrorIs(t, err, services.ErrCopiesOnLoan)
+	// Nothing is deleted
+	var bookType models.BookType
+	require.NoError(t, db.First(&bookType, 2).Error)
+	var withdrawn int64
+	require.NoError(t, db.Unscoped().Model(&models.Book{}).Where("book_type_id = ? AND status = 4", 2).Count(&withdrawn).Error)
+	assert.Zero(t, withdrawn)
+}
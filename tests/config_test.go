@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"library/config"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+port: "9000"
+database:
+  driver: sqlite
+  url: library.db
+auth:
+  secret: from-file
+fines:
+  daily_rate: 50
+  grace_days: 2
+`)
+	t.Setenv("SECRET", "from-env")
+	t.Setenv("FINE_DAILY_RATE", "75")
+	t.Setenv("CORS_ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("DRAIN_DELAY_SECONDS", "0")
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "9000", cfg.Port)
+	assert.Equal(t, "sqlite", cfg.Database.Driver)
+	assert.Equal(t, "from-env", cfg.Auth.Secret)
+	assert.Equal(t, int64(75), cfg.Fines.DailyRate)
+	assert.Equal(t, 2, cfg.Fines.GraceDays)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.CORS.AllowOrigins)
+	assert.Zero(t, cfg.Server.DrainDelay())
+	// Untouched settings keep their defaults
+	assert.Equal(t, 15, cfg.Auth.AccessTokenMinutes)
+	assert.Equal(t, 90, cfg.Purge.RetentionDays)
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	t.Setenv("DB_DRIVER", "mysql")
+	t.Setenv("DB_URL", "")
+	t.Setenv("SECRET", "")
+	t.Setenv("PORT", "http")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("DRAIN_DELAY_SECONDS", "-1")
+
+	_, err := config.Load("")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.driver")
+	assert.Contains(t, err.Error(), "DB_URL")
+	assert.Contains(t, err.Error(), "SECRET")
+	assert.Contains(t, err.Error(), "port")
+	assert.Contains(t, err.Error(), "log.level")
+	assert.Contains(t, err.Error(), "drain_delay_seconds")
+}
+
+func TestLoadConfigBadNumber(t *testing.T) {
+	t.Setenv("DB_URL", "library.db")
+	t.Setenv("SECRET", "secret")
+	t.Setenv("PURGE_RETENTION_DAYS", "ninety")
+
+	_, err := config.Load("")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PURGE_RETENTION_DAYS")
+}
+
+func TestConfigYAMLRedactsSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.URL = "host=db user=library password=hunter2"
+	cfg.Auth.Secret = "signing-secret"
+
+	out, err := cfg.YAML()
+	require.NoError(t, err)
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "signing-secret")
+	assert.Contains(t, out, "[REDACTED]")
+	assert.Contains(t, out, "daily_rate: 25")
+
+	// The original is left alone
+	assert.Equal(t, "signing-secret", cfg.Auth.Secret)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ErrorEnvelope struct {
+	Error struct {
+		Status  int            `json:"status"`
+		Code    string         `json:"code"`
+		Message string         `json:"message"`
+		Details map[string]any `json:"details"`
+	} `json:"error"`
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) ErrorEnvelope {
+	var envelope ErrorEnvelope
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&envelope))
+	assert.Equal(t, w.Code, envelope.Error.Status)
+	return envelope
+}
+
+func TestErrorNoCopyAvailable(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := postIDs(router, "/book/borrow", []int{3})
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	envelope := decodeError(t, w)
+	assert.Equal(t, "NO_COPY_AVAILABLE", envelope.Error.Code)
+	assert.NotEmpty(t, envelope.Error.Message)
+	assert.Equal(t, float64(3), envelope.Error.Details["book_type_id"])
+}
+
+func TestErrorReturnRecordNotOwned(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := postIDs(router, "/record/return", []int{2})
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	envelope := decodeError(t, w)
+	assert.Equal(t, "RECORD_NOT_OWNED", envelope.Error.Code)
+	assert.NotContains(t, envelope.Error.Message, "extend")
+	assert.Equal(t, float64(2), envelope.Error.Details["record_id"])
+}
+
+func TestErrorExtendLoanOverdue(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := postIDs(router, "/record/extend", []int{1})
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	envelope := decodeError(t, w)
+	assert.Equal(t, "LOAN_OVERDUE", envelope.Error.Code)
+	assert.Equal(t, float64(1), envelope.Error.Details["record_id"])
+}
+
+func TestErrorCancelHoldNotFound(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockHoldDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := postIDs(router, "/hold/cancel", []int{99})
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	envelope := decodeError(t, w)
+	assert.Equal(t, "HOLD_NOT_FOUND", envelope.Error.Code)
+	assert.Equal(t, float64(99), envelope.Error.Details["hold_id"])
+}
+
+func TestErrorInvalidPayload(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	req, _ := http.NewRequest("POST", "/record/return", bytes.NewBufferString("{"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	envelope := decodeError(t, w)
+	assert.Equal(t, "INVALID_PAYLOAD", envelope.Error.Code)
+	assert.Nil(t, envelope.Error.Details)
+}
+
+func TestErrorRouteNotFound(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	req, _ := http.NewRequest("GET", "/no/such/path", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.Equal(t, "ROUTE_NOT_FOUND", decodeError(t, w).Error.Code)
+}
+
+func TestEmptyListsAreNotErrors(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	// A page past the last record is empty, not missing
+	requestBody, _ := json.Marshal(map[string]interface{}{"title": "", "page_size": 10, "page": 5})
+	req, _ := http.NewRequest("POST", "/record/list", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"records": [], "total": 3, "next_cursor": "", "has_more": false}`, w.Body.String())
+
+	requestBody, _ = json.Marshal(map[string]interface{}{"title": "No Such Title", "page_size": 10, "page": 0})
+	req, _ = http.NewRequest("POST", "/book/list", bytes.NewBuffer(requestBody))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"books": [], "total": 0, "next_cursor": "", "has_more": false}`, w.Body.String())
+}
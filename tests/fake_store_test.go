@@ -0,0 +1,332 @@
+package tests
+
+import (
+	"library/models"
+	"library/repositories"
+	"time"
+)
+
+// fakeStore keeps the rows the circulation service touches in memory, so the
+// rules can be checked without a database. Repository methods the service
+// never calls fall through to the nil embedded interfaces.
+type fakeStore struct {
+	users    map[uint]models.User
+	types    map[uint]models.BookType
+	books    map[uint]models.Book
+	records  map[uint]models.Record
+	holds    map[uint]models.Hold
+	fines    []models.Fine
+	policies []models.LoanPolicy
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:   map[uint]models.User{},
+		types:   map[uint]models.BookType{},
+		books:   map[uint]models.Book{},
+		records: map[uint]models.Record{},
+		holds:   map[uint]models.Hold{},
+	}
+}
+
+func (s *fakeStore) Books() repositories.BookRepository                  { return fakeBooks{s: s} }
+func (s *fakeStore) Records() repositories.RecordRepository              { return fakeRecords{s: s} }
+func (s *fakeStore) Users() repositories.UserRepository                  { return fakeUsers{s: s} }
+func (s *fakeStore) Sessions() repositories.SessionRepository            { return fakeSessions{} }
+func (s *fakeStore) Holds() repositories.HoldRepository                  { return fakeHolds{s: s} }
+func (s *fakeStore) Fines() repositories.FineRepository                  { return fakeFines{s: s} }
+func (s *fakeStore) Policies() repositories.PolicyRepository             { return fakePolicies{s: s} }
+func (s *fakeStore) Audit() repositories.AuditRepository                 { return nil }
+func (s *fakeStore) Trash() repositories.TrashRepository                 { return nil }
+func (s *fakeStore) Transaction(fn func(repositories.Store) error) error { return fn(s) }
+
+type fakeBooks struct {
+	repositories.BookRepository
+	s *fakeStore
+}
+
+func (r fakeBooks) FindBookType(id uint) (models.BookType, error) {
+	bookType, ok := r.s.types[id]
+	if !ok {
+		return models.BookType{}, repositories.ErrNotFound
+	}
+	return bookType, nil
+}
+
+func (r fakeBooks) FindCopyByBarcode(barcode string) (models.Book, error) {
+	for _, book := range r.s.books {
+		if book.Barcode != nil && *book.Barcode == barcode {
+			return book, nil
+		}
+	}
+	return models.Book{}, repositories.ErrNotFound
+}
+
+func (r fakeBooks) FindCopies(ids []uint) ([]models.Book, error) {
+	var books []models.Book
+	for _, id := range ids {
+		if book, ok := r.s.books[id]; ok {
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
+
+func (r fakeBooks) FirstCopyWithStatus(bookTypeID, status uint) (models.Book, error) {
+	found := models.Book{}
+	for _, book := range r.s.books {
+		if book.BookTypeID == bookTypeID && book.Status == status && (found.ID == 0 || book.ID < found.ID) {
+			found = book
+		}
+	}
+	if found.ID == 0 {
+		return found, repositories.ErrNotFound
+	}
+	return found, nil
+}
+
+func (r fakeBooks) ClaimCopy(id, fromStatus uint) (bool, error) {
+	book, ok := r.s.books[id]
+	if !ok || book.Status != fromStatus {
+		return false, nil
+	}
+	book.Status = 2
+	r.s.books[id] = book
+	return true, nil
+}
+
+func (r fakeBooks) SetCopyStatus(id, fromStatus, status uint) (bool, error) {
+	book, ok := r.s.books[id]
+	if !ok || book.Status != fromStatus {
+		return false, nil
+	}
+	book.Status = status
+	r.s.books[id] = book
+	return true, nil
+}
+
+type fakeRecords struct {
+	repositories.RecordRepository
+	s *fakeStore
+}
+
+func (r fakeRecords) FindByIDs(ids []uint) ([]models.Record, error) {
+	var records []models.Record
+	for _, id := range ids {
+		if record, ok := r.s.records[id]; ok {
+			record.Book = r.s.books[record.BookID]
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (r fakeRecords) FindOpenByCopy(bookID uint) (models.Record, error) {
+	for _, record := range r.s.records {
+		if record.BookID == bookID && !record.IsClosed {
+			return record, nil
+		}
+	}
+	return models.Record{}, repositories.ErrNotFound
+}
+
+func (r fakeRecords) CountOpen(userIDs ...uint) (int64, error) {
+	count := int64(0)
+	for _, record := range r.s.records {
+		for _, userID := range userIDs {
+			if record.UserID == userID && !record.IsClosed {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+func (r fakeRecords) Create(records []models.Record) error {
+	for i := range records {
+		if _, err := r.FindOpenByCopy(records[i].BookID); err == nil {
+			return repositories.ErrDuplicate
+		}
+		records[i].ID = uint(len(r.s.records) + 1)
+		r.s.records[records[i].ID] = records[i]
+	}
+	return nil
+}
+
+func (r fakeRecords) Close(ids []uint, returnedAt time.Time) ([]models.Record, error) {
+	var records []models.Record
+	for _, id := range ids {
+		record, ok := r.s.records[id]
+		if !ok || record.IsClosed {
+			continue
+		}
+		record.IsClosed = true
+		record.ReturnedAt = &returnedAt
+		r.s.records[id] = record
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (r fakeRecords) Renew(id uint, renewalCount int, dueAt time.Time) (bool, error) {
+	record, ok := r.s.records[id]
+	if !ok || record.IsClosed || record.RenewalCount != renewalCount {
+		return false, nil
+	}
+	record.DueAt = dueAt
+	record.RenewalCount++
+	r.s.records[id] = record
+	return true, nil
+}
+
+type fakeUsers struct {
+	repositories.UserRepository
+	s *fakeStore
+}
+
+func (r fakeUsers) FindByID(id uint) (models.User, error) {
+	user, ok := r.s.users[id]
+	if !ok {
+		return models.User{}, repositories.ErrNotFound
+	}
+	return user, nil
+}
+
+type fakeSessions struct {
+	repositories.SessionRepository
+}
+
+type fakeHolds struct {
+	repositories.HoldRepository
+	s *fakeStore
+}
+
+func (r fakeHolds) ReadyFor(userID, bookTypeID uint) (models.Hold, error) {
+	for _, hold := range r.s.holds {
+		if hold.UserID == userID && hold.BookTypeID == bookTypeID && hold.Status == 2 {
+			return hold, nil
+		}
+	}
+	return models.Hold{}, repositories.ErrNotFound
+}
+
+func (r fakeHolds) CountReadyForCopy(userID, bookID uint) (int64, error) {
+	count := int64(0)
+	for _, hold := range r.s.holds {
+		if hold.UserID == userID && hold.Status == 2 && hold.BookID != nil && *hold.BookID == bookID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r fakeHolds) FirstWaiting(bookTypeID uint) (models.Hold, error) {
+	found := models.Hold{}
+	for _, hold := range r.s.holds {
+		if hold.BookTypeID == bookTypeID && hold.Status == 1 && (found.ID == 0 || hold.ID < found.ID) {
+			found = hold
+		}
+	}
+	if found.ID == 0 {
+		return found, repositories.ErrNotFound
+	}
+	return found, nil
+}
+
+func (r fakeHolds) ExpiredReady(now time.Time) ([]models.Hold, error) {
+	var holds []models.Hold
+	for _, hold := range r.s.holds {
+		if hold.Status == 2 && hold.ExpiresAt != nil && hold.ExpiresAt.Before(now) {
+			holds = append(holds, hold)
+		}
+	}
+	return holds, nil
+}
+
+func (r fakeHolds) MarkReady(id, bookID uint, readyAt, expiresAt time.Time) (bool, error) {
+	hold, ok := r.s.holds[id]
+	if !ok || hold.Status != 1 {
+		return false, nil
+	}
+	hold.Status, hold.BookID, hold.ReadyAt, hold.ExpiresAt = 2, &bookID, &readyAt, &expiresAt
+	r.s.holds[id] = hold
+	return true, nil
+}
+
+func (r fakeHolds) Expire(id uint) (bool, error) {
+	hold, ok := r.s.holds[id]
+	if !ok || hold.Status != 2 {
+		return false, nil
+	}
+	hold.Status = 5
+	r.s.holds[id] = hold
+	return true, nil
+}
+
+func (r fakeHolds) FindByIDs(ids []uint) ([]models.Hold, error) {
+	var holds []models.Hold
+	for _, id := range ids {
+		if hold, ok := r.s.holds[id]; ok {
+			holds = append(holds, hold)
+		}
+	}
+	return holds, nil
+}
+
+func (r fakeHolds) Cancel(id, fromStatus uint) (bool, error) {
+	hold, ok := r.s.holds[id]
+	if !ok || hold.Status != fromStatus {
+		return false, nil
+	}
+	hold.Status = 4
+	r.s.holds[id] = hold
+	return true, nil
+}
+
+func (r fakeHolds) Fulfil(userID uint, bookTypeIDs []uint) error {
+	for id, hold := range r.s.holds {
+		for _, bookTypeID := range bookTypeIDs {
+			if hold.UserID == userID && hold.BookTypeID == bookTypeID && (hold.Status == 1 || hold.Status == 2) {
+				hold.Status = 3
+				r.s.holds[id] = hold
+			}
+		}
+	}
+	return nil
+}
+
+type fakeFines struct {
+	repositories.FineRepository
+	s *fakeStore
+}
+
+func (r fakeFines) OutstandingBalance(userID uint) (int64, error) {
+	balance := int64(0)
+	for _, fine := range r.s.fines {
+		if fine.UserID == userID && fine.Status == 1 {
+			balance += fine.Outstanding()
+		}
+	}
+	return balance, nil
+}
+
+func (r fakeFines) Create(fine *models.Fine) error {
+	fine.ID = uint(len(r.s.fines) + 1)
+	r.s.fines = append(r.s.fines, *fine)
+	return nil
+}
+
+type fakePolicies struct {
+	repositories.PolicyRepository
+	s *fakeStore
+}
+
+func (r fakePolicies) Candidates(patronCategory, itemType string) ([]models.LoanPolicy, error) {
+	var policies []models.LoanPolicy
+	for _, policy := range r.s.policies {
+		if policy.Matches(patronCategory, itemType) >= 0 {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
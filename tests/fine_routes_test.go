@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"library/logging"
+	"library/models"
+	"library/repositories"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type FineListResponse struct {
+	Fines   []models.FineResponse `json:"fines"`
+	Total   int                   `json:"total"`
+	Balance int64                 `json:"balance"`
+}
+
+func TestReturnRecordsOverdueCreatesFine(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{1}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/record/return", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var fine models.Fine
+	require.NoError(t, db.Where("record_id = ?", 1).First(&fine).Error)
+	assert.Equal(t, uint(1), fine.UserID)
+	assert.Equal(t, int64(2000), fine.Amount) // capped at the default maximum
+}
+
+func TestBorrowBooksBlockedByFines(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{1}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/book/borrow", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGetFineList(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"page_size": 10,
+		"page":      0,
+		"status":    0,
+	})
+	req, _ := http.NewRequest("POST", "/fine/list", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var fineListResponse FineListResponse
+	err := json.NewDecoder(w.Body).Decode(&fineListResponse)
+	require.NoError(t, err) // Ensure JSON decoding is successful
+
+	assert.Equal(t, 1, fineListResponse.Total)
+	assert.Equal(t, int64(1500), fineListResponse.Balance)
+}
+
+func TestPayFinesExceedsBalance(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"user_id": 1,
+		"amount":  5000,
+	})
+	req, _ := http.NewRequest("POST", "/fine/pay", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWaiveFinesSuccess(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{1}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/fine/waive", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var fine models.Fine
+	require.NoError(t, db.First(&fine, 1).Error)
+	assert.Equal(t, uint(3), fine.Status)
+	assert.Equal(t, int64(0), fine.Outstanding())
+}
+
+func TestPayFinesPartThenRest(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := serve(router, "POST", "/fine/pay", map[string]any{"user_id": 1, "amount": 500})
+	require.Equal(t, http.StatusOK, w.Code)
+	var fine models.Fine
+	require.NoError(t, db.First(&fine, 1).Error)
+	assert.Equal(t, int64(500), fine.Paid)
+	assert.Equal(t, uint(1), fine.Status)
+
+	w = serve(router, "POST", "/fine/pay", map[string]any{"user_id": 1, "amount": 1000})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, db.First(&fine, 1).Error)
+	assert.Equal(t, int64(1500), fine.Paid)
+	assert.Equal(t, uint(2), fine.Status)
+}
+
+// A payment or waiver working from a stale read of a fine must not undo or
+// repeat what was written since
+func TestFineUpdatesBuildOnStoredAmounts(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	fines := repositories.NewStore(db).Fines()
+
+	paid, err := fines.ApplyPayment(1, 1000)
+	require.NoError(t, err)
+	assert.True(t, paid)
+	// Both read 1500 outstanding; only 500 is left after the first
+	paid, err = fines.ApplyPayment(1, 1000)
+	require.NoError(t, err)
+	assert.False(t, paid)
+
+	waived, err := fines.Waive(1, 2, "")
+	require.NoError(t, err)
+	assert.True(t, waived)
+	waived, err = fines.Waive(1, 2, "")
+	require.NoError(t, err)
+	assert.False(t, waived)
+
+	var fine models.Fine
+	require.NoError(t, db.First(&fine, 1).Error)
+	assert.Equal(t, int64(1000), fine.Paid)
+	assert.Equal(t, int64(500), fine.Waived)
+	assert.Equal(t, uint(3), fine.Status)
+}
+
+func TestWaiveFinesUnknownFine(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := serve(router, "POST", "/fine/waive", map[string]any{"ids": []int{1, 999}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "FINE_NOT_FOUND", decodeError(t, w).Error.Code)
+
+	var fine models.Fine
+	require.NoError(t, db.First(&fine, 1).Error)
+	assert.Equal(t, uint(1), fine.Status)
+}
+
+func TestWaiveFinesSettled(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	require.Equal(t, http.StatusOK, serve(router, "POST", "/fine/waive", map[string]any{"ids": []int{1}}).Code)
+	w := serve(router, "POST", "/fine/waive", map[string]any{"ids": []int{1}})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "FINE_SETTLED", decodeError(t, w).Error.Code)
+}
+
+// Patrons reach the staff routes signed in as themselves
+func TestPayAndWaiveFinesForbiddenForPatrons(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockFineDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	auth := mockAuth(db)
+	auth.Staff = MockCheckAuth
+	router := SetupMockRouterWithAuth(db, logging.Discard(), auth)
+
+	w := serve(router, "POST", "/fine/pay", map[string]any{"user_id": 1, "amount": 1500})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = serve(router, "POST", "/fine/waive", map[string]any{"ids": []int{1}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var fine models.Fine
+	require.NoError(t, db.First(&fine, 1).Error)
+	assert.Equal(t, int64(1500), fine.Outstanding())
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"database/sql"
+	"library/controllers"
+	"library/logging"
+	"library/middlewares"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveness(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReadiness(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReadinessDatabaseDown(t *testing.T) {
+	db := SetupMockDB()
+	router := SetupMockRouter(db)
+	db.ConnPool.(*sql.DB).Close()
+
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "DATABASE_UNAVAILABLE", decodeError(t, w).Error.Code)
+}
+
+func TestReadinessDraining(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	healthController := controllers.NewHealthController(db, logging.Discard())
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
+	router.GET("/readyz", healthController.Readiness)
+
+	healthController.Drain()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "SERVER_DRAINING", decodeError(t, w).Error.Code)
+}
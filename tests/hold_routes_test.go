@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"library/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type HoldPlaceResponse struct {
+	Holds   []models.HoldResponse `json:"data"`
+	Message string                `json:"message"`
+}
+
+func TestPlaceHoldsAvailableBook(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockHoldDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{1}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/hold/place", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestPlaceHoldsSuccess(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockHoldDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{3}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/hold/place", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var holdPlaceResponse HoldPlaceResponse
+	err := json.NewDecoder(w.Body).Decode(&holdPlaceResponse)
+	require.NoError(t, err) // Ensure JSON decoding is successful
+
+	require.Len(t, holdPlaceResponse.Holds, 1)
+	assert.Equal(t, "Waiting", holdPlaceResponse.Holds[0].Status)
+	assert.Equal(t, 2, holdPlaceResponse.Holds[0].Position)
+}
+
+func TestCancelHoldsOtherUserHold(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockHoldDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{1}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/hold/cancel", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestReturnRecordsSetsAsideForHold(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockHoldDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{3}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/record/return", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var book models.Book
+	require.NoError(t, db.First(&book, 6).Error)
+	assert.Equal(t, uint(3), book.Status)
+
+	var hold models.Hold
+	require.NoError(t, db.First(&hold, 1).Error)
+	assert.Equal(t, uint(2), hold.Status)
+	require.NotNil(t, hold.BookID)
+	assert.Equal(t, uint(6), *hold.BookID)
+}
@@ -0,0 +1,25 @@
+package tests
+
+import (
+	"library/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidISBN10(t *testing.T) {
+	assert.True(t, models.ValidISBN10(models.NormalizeISBN("0-306-40615-2")))
+	assert.True(t, models.ValidISBN10(models.NormalizeISBN("0-8044-2957-x")))
+	assert.False(t, models.ValidISBN10(models.NormalizeISBN("0-306-40615-3")))
+	assert.False(t, models.ValidISBN10("030640615"))
+}
+
+func TestValidISBN13(t *testing.T) {
+	assert.True(t, models.ValidISBN13(models.NormalizeISBN("978-0-306-40615-7")))
+	assert.False(t, models.ValidISBN13(models.NormalizeISBN("978-0-306-40615-8")))
+	assert.False(t, models.ValidISBN13("97803064061X7"))
+}
+
+func TestISBN10To13(t *testing.T) {
+	assert.Equal(t, "9780306406157", models.ISBN10To13("0306406152"))
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"library/logging"
+	"library/models"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeLogLines parses every JSON line the logger wrote
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+// findLogLine returns the first line whose key holds value
+func findLogLine(lines []map[string]any, key string, value any) map[string]any {
+	for _, line := range lines {
+		if line[key] == value {
+			return line
+		}
+	}
+	return nil
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	var buf bytes.Buffer
+	router := SetupMockRouterWithLogger(db, logging.New(&buf, slog.LevelDebug))
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	requestID := w.Header().Get(logging.RequestIDHeader)
+	assert.Len(t, requestID, 32)
+
+	access := findLogLine(decodeLogLines(t, &buf), "msg", "request")
+	require.NotNil(t, access)
+	assert.Equal(t, requestID, access["request_id"])
+	assert.Equal(t, "GET", access["method"])
+	assert.Equal(t, "/healthz", access["route"])
+	assert.Equal(t, float64(http.StatusOK), access["status"])
+	assert.Contains(t, access, "latency_ms")
+	assert.NotContains(t, access, "user_id")
+}
+
+func TestRequestIDFromHeader(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	req.Header.Set(logging.RequestIDHeader, "upstream-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "upstream-123", w.Header().Get(logging.RequestIDHeader))
+
+	// Unprintable or oversized IDs are replaced rather than logged
+	for _, requestID := range []string{"has space", strings.Repeat("a", 129)} {
+		req, _ := http.NewRequest("GET", "/healthz", nil)
+		req.Header.Set(logging.RequestIDHeader, requestID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Len(t, w.Header().Get(logging.RequestIDHeader), 32)
+	}
+}
+
+func TestBorrowLogsEvent(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	var buf bytes.Buffer
+	router := SetupMockRouterWithLogger(db, logging.New(&buf, slog.LevelInfo))
+
+	requestBody, _ := json.Marshal(map[string][]int{"ids": {1}})
+	req, _ := http.NewRequest("POST", "/book/borrow", bytes.NewBuffer(requestBody))
+	req.Header.Set(logging.RequestIDHeader, "borrow-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	lines := decodeLogLines(t, &buf)
+	event := findLogLine(lines, "event", logging.EventBorrow)
+	require.NotNil(t, event)
+	assert.Equal(t, "INFO", event["level"])
+	assert.Equal(t, "borrow-1", event["request_id"])
+	assert.Equal(t, float64(1), event["user_id"])
+	assert.Equal(t, []any{float64(1)}, event["book_type_ids"])
+	assert.Len(t, event["record_ids"], 1)
+	assert.Equal(t, float64(1), event["count"])
+
+	access := findLogLine(lines, "msg", "request")
+	require.NotNil(t, access)
+	assert.Equal(t, "/book/borrow", access["route"])
+	assert.Equal(t, float64(1), access["user_id"])
+}
+
+func TestReturnLogsReturnEvent(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	var buf bytes.Buffer
+	router := SetupMockRouterWithLogger(db, logging.New(&buf, slog.LevelInfo))
+
+	requestBody, _ := json.Marshal(map[string][]int{"ids": {3}})
+	req, _ := http.NewRequest("POST", "/record/return", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	lines := decodeLogLines(t, &buf)
+	assert.Nil(t, findLogLine(lines, "event", logging.EventExtend))
+	event := findLogLine(lines, "event", logging.EventReturn)
+	require.NotNil(t, event)
+	assert.Equal(t, []any{float64(3)}, event["record_ids"])
+}
+
+// Events raised deep in the services carry the request that caused them
+func TestReturnLogsFineAndHoldEventsWithRequestID(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	var buf bytes.Buffer
+	router := SetupMockRouterWithLogger(db, logging.New(&buf, slog.LevelInfo))
+	require.NoError(t, db.Omit("User", "BookType").Create(&models.Hold{UserID: 2, BookTypeID: 1, Status: 1}).Error)
+
+	requestBody, _ := json.Marshal(map[string][]int{"ids": {1}})
+	req, _ := http.NewRequest("POST", "/record/return", bytes.NewBuffer(requestBody))
+	req.Header.Set(logging.RequestIDHeader, "return-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	lines := decodeLogLines(t, &buf)
+	fine := findLogLine(lines, "event", logging.EventFineAssessed)
+	require.NotNil(t, fine)
+	assert.Equal(t, "return-1", fine["request_id"])
+	assert.Equal(t, float64(1), fine["user_id"])
+	assert.Equal(t, float64(1), fine["record_id"])
+
+	ready := findLogLine(lines, "event", logging.EventHoldReady)
+	require.NotNil(t, ready)
+	assert.Equal(t, "return-1", ready["request_id"])
+	assert.Equal(t, float64(2), ready["patron_id"])
+}
+
+func TestErrorsLogAtWarnLevel(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	var buf bytes.Buffer
+	router := SetupMockRouterWithLogger(db, logging.New(&buf, slog.LevelWarn))
+
+	req, _ := http.NewRequest("POST", "/record/return", bytes.NewBufferString("{"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	lines := decodeLogLines(t, &buf)
+	invalid := findLogLine(lines, "msg", "Invalid return request payload")
+	require.NotNil(t, invalid)
+	assert.Equal(t, "WARN", invalid["level"])
+	assert.Contains(t, invalid, "error")
+	access := findLogLine(lines, "msg", "request")
+	require.NotNil(t, access)
+	assert.Equal(t, "WARN", access["level"])
+}
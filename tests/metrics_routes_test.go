@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrapeMetrics(t *testing.T, router *gin.Engine) string {
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func postIDs(router *gin.Engine, path string, ids []int) *httptest.ResponseRecorder {
+	requestBody, _ := json.Marshal(map[string][]int{"ids": ids})
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMetricsLoanGauges(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	body := scrapeMetrics(t, router)
+	assert.Contains(t, body, "library_active_loans 3\n")
+	assert.Contains(t, body, "library_overdue_loans 1\n")
+	assert.Contains(t, body, `go_sql_open_connections{db_name="library"}`)
+}
+
+func TestMetricsCirculationCounters(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	require.Equal(t, http.StatusOK, postIDs(router, "/book/borrow", []int{1}).Code)
+	require.Equal(t, http.StatusNotFound, postIDs(router, "/book/borrow", []int{3}).Code)
+	require.Equal(t, http.StatusOK, postIDs(router, "/record/extend", []int{3}).Code)
+	require.Equal(t, http.StatusOK, postIDs(router, "/record/return", []int{3}).Code)
+
+	body := scrapeMetrics(t, router)
+	assert.Contains(t, body, `library_borrows_total{channel="self"} 1`)
+	assert.Contains(t, body, `library_borrow_failures_total{reason="no_copy_available"} 1`)
+	assert.Contains(t, body, "library_extensions_total 1")
+	assert.Contains(t, body, `library_returns_total{channel="self"} 1`)
+	assert.Contains(t, body, "library_active_loans 3\n")
+}
+
+func TestMetricsRequestDuration(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req, _ = http.NewRequest("GET", "/no/such/path/42", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	body := scrapeMetrics(t, router)
+	assert.Contains(t, body, `library_http_request_duration_seconds_count{method="GET",route="/healthz",status="200"} 1`)
+	assert.Contains(t, body, `library_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, "/no/such/path")
+}
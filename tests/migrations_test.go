@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"database/sql"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"library/initializers"
+	"library/migrations"
+	"library/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMigrationVersionsUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, migration := range migrations.All() {
+		assert.Len(t, migration.Version, 14)
+		assert.False(t, seen[migration.Version], "duplicate migration version %s", migration.Version)
+		assert.NotNil(t, migration.Up)
+		seen[migration.Version] = true
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	path, err := migrations.Create(dir, "Rename Book Columns", now)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "20261018093000_rename_book_columns.go"), path)
+
+	source, err := os.ReadFile(path)
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), path, source, 0)
+	assert.NoError(t, err)
+
+	// Never overwrite an existing migration
+	_, err = migrations.Create(dir, "rename book columns", now)
+	assert.Error(t, err)
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	defer PrepareMockUserDB(db)
+
+	total := len(migrations.All())
+	_, err := migrations.Down(db, total)
+	require.NoError(t, err)
+
+	applied, err := migrations.Up(db, 0)
+	require.NoError(t, err)
+	assert.Len(t, applied, total)
+	assert.True(t, db.Migrator().HasTable("books"))
+	assert.True(t, db.Migrator().HasTable("refresh_tokens"))
+
+	// Running again is a no-op
+	applied, err = migrations.Up(db, 0)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrations.Status(db)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, status.Version)
+	}
+
+	rolledBack, err := migrations.Down(db, total)
+	require.NoError(t, err)
+	assert.Len(t, rolledBack, total)
+	assert.False(t, db.Migrator().HasTable("books"))
+
+	statuses, err = migrations.Status(db)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.Nil(t, status.AppliedAt, status.Version)
+	}
+}
+
+// A database from before the open record index that lent a copy twice still
+// migrates: the extra open records are closed so the index can build
+func TestMigrateClosesDuplicateOpenRecords(t *testing.T) {
+	db, err := initializers.OpenDB("sqlite", "file:migrate_duplicates?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.ConnPool.(*sql.DB).Close()
+
+	for _, statement := range []string{
+		`CREATE TABLE books (id integer PRIMARY KEY, book_type_id integer, status integer)`,
+		`CREATE TABLE records (id integer PRIMARY KEY, user_id integer, book_id integer, returned_at datetime, due_at datetime, is_closed numeric DEFAULT false)`,
+		`INSERT INTO books (id, book_type_id, status) VALUES (1, 1, 1), (2, 1, 2)`,
+		`INSERT INTO records (id, user_id, book_id, is_closed) VALUES (1, 1, 1, false), (2, 2, 1, false), (3, 1, 2, false)`,
+	} {
+		require.NoError(t, db.Exec(statement).Error)
+	}
+
+	_, err = migrations.Up(db, 0)
+	require.NoError(t, err)
+
+	var records []models.Record
+	require.NoError(t, db.Unscoped().Order("id").Find(&records).Error)
+	require.Len(t, records, 3)
+	assert.False(t, records[0].IsClosed)
+	assert.True(t, records[1].IsClosed)
+	assert.NotNil(t, records[1].ReturnedAt)
+	assert.False(t, records[2].IsClosed)
+
+	var book models.Book
+	require.NoError(t, db.Unscoped().First(&book, 1).Error)
+	assert.Equal(t, uint(2), book.Status)
+	assert.True(t, db.Migrator().HasIndex("records", "idx_records_open_book"))
+}
+
+// schemaModels are the models the application reads and writes
+var schemaModels = []any{
+	&models.User{}, &models.AuditEntry{}, &models.Session{}, &models.RefreshToken{},
+	&models.Author{}, &models.Subject{}, &models.BookType{}, &models.Book{},
+	&models.Record{}, &models.Hold{}, &models.Fine{}, &models.FinePayment{}, &models.LoanPolicy{},
+}
+
+// The migrations build the same tables, columns and indexes as the models.
+// Both sides are fresh SQLite databases of their own, whatever TEST_DB_DRIVER
+// says, since the index definitions are read from sqlite_master.
+func TestMigratedSchemaMatchesModels(t *testing.T) {
+	modelled, err := initializers.OpenDB("sqlite", "file:schema_models?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer modelled.ConnPool.(*sql.DB).Close()
+	require.NoError(t, modelled.AutoMigrate(schemaModels...))
+	want := describeSchema(t, modelled)
+
+	migrated, err := initializers.OpenDB("sqlite", "file:schema_migrated?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer migrated.ConnPool.(*sql.DB).Close()
+	_, err = migrations.Up(migrated, 0)
+	require.NoError(t, err)
+	assert.Equal(t, want, describeSchema(t, migrated))
+
+	// Rolling back any number of migrations and up again ends in the same place
+	for steps := 1; steps < len(migrations.All()); steps++ {
+		_, err = migrations.Down(migrated, steps)
+		require.NoError(t, err)
+		_, err = migrations.Up(migrated, 0)
+		require.NoError(t, err)
+		assert.Equal(t, want, describeSchema(t, migrated), "after %d down and up", steps)
+	}
+}
+
+// describeSchema lists every column and index of a SQLite database's tables,
+// sorted and with quoting normalized so two builds of one schema compare equal
+func describeSchema(t *testing.T, db *gorm.DB) []string {
+	tables, err := db.Migrator().GetTables()
+	require.NoError(t, err)
+
+	var schema []string
+	for _, table := range tables {
+		if table == "schema_migrations" || strings.HasPrefix(table, "sqlite_") {
+			continue
+		}
+		columns, err := db.Migrator().ColumnTypes(table)
+		require.NoError(t, err)
+		for _, column := range columns {
+			nullable, _ := column.Nullable()
+			unique, _ := column.Unique()
+			primaryKey, _ := column.PrimaryKey()
+			defaultValue, _ := column.DefaultValue()
+			schema = append(schema, fmt.Sprintf("column %s.%s %s nullable=%t unique=%t primary=%t default=%q",
+				table, column.Name(), column.DatabaseTypeName(), nullable, unique, primaryKey, defaultValue))
+		}
+	}
+
+	var indexes []struct{ Name, SQL string }
+	require.NoError(t, db.Raw("SELECT name, sql FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL AND tbl_name <> 'schema_migrations'").Scan(&indexes).Error)
+	normalize := strings.NewReplacer("`", "", `"`, "", " ", "", "IFNOTEXISTS", "")
+	for _, index := range indexes {
+		schema = append(schema, "index "+index.Name+" "+normalize.Replace(index.SQL))
+	}
+
+	sort.Strings(schema)
+	return schema
+}
@@ -33,33 +33,39 @@ var MockBookType = []models.BookType{
 var MockBook = []models.Book{
 	{
 		BookTypeID: 1,
+		Barcode:    barcode("LIB00000001"),
 		Status:     1,
 	},
 	{
 		BookTypeID: 1,
+		Barcode:    barcode("LIB00000002"),
 		Status:     2,
 	},
 	{
 		BookTypeID: 2,
+		Barcode:    barcode("LIB00000003"),
 		Status:     1,
 	},
 	{
 		BookTypeID: 2,
+		Barcode:    barcode("LIB00000004"),
 		Status:     1,
 	},
 	{
 		BookTypeID: 2,
+		Barcode:    barcode("LIB00000005"),
 		Status:     2,
 	},
 	{
 		BookTypeID: 3,
+		Barcode:    barcode("LIB00000006"),
 		Status:     2,
 	},
 }
 
 var layout = "2006-01-02 15:04:05" // Go's reference time format
 var overdueAt = "2024-02-01 10:20:40"
-var dueAt = "2025-04-01 10:20:40"
+var dueAt = time.Now().AddDate(0, 6, 0).Format(layout) // keeps open loans from going overdue
 var returnedAt = "2023-04-01 10:20:40"
 var parsedOverdueAt, _ = time.Parse(layout, overdueAt)
 var parsedDueAt, _ = time.Parse(layout, dueAt)
@@ -97,3 +103,38 @@ var MockRecord = []models.Record{
 		IsClosed:   true,
 	},
 }
+
+var MockHold = []models.Hold{
+	// user 2 waits for the only copy of Mock Book 3
+	{
+		UserID:     2,
+		BookTypeID: 3,
+		Status:     1,
+	},
+}
+
+var MockFine = []models.Fine{
+	// user 1 owes more than the default block threshold
+	{
+		UserID:      1,
+		RecordID:    4,
+		Amount:      1500,
+		DaysOverdue: 60,
+		Status:      1,
+	},
+}
+
+func barcode(value string) *string {
+	return &value
+}
+
+var MockLoanPolicy = []models.LoanPolicy{
+	// standard patrons may hold a single loan with no renewals
+	{
+		PatronCategory: "standard",
+		LoanDays:       14,
+		RenewalDays:    7,
+		MaxRenewals:    0,
+		MaxLoans:       1,
+	},
+}
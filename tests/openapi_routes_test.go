@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"database/sql"
+	"encoding/json"
+	"library/controllers"
+	"library/logging"
+	"library/metrics"
+	"library/middlewares"
+	"library/repositories"
+	"library/routes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fetchSpec(t *testing.T, router *gin.Engine) map[string]any {
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var spec map[string]any
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&spec))
+	return spec
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// Every route main serves is documented and every documented route exists.
+// The router is built by the same RegisterRoutes call as main, with the real
+// auth middleware on every group.
+func TestOpenAPIMatchesRouter(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	store := repositories.NewStore(db)
+	checkAuth := middlewares.CheckAuth(db, testConfig.Auth)
+	router := gin.New()
+	routes.RegisterRoutes(router, routes.Deps{
+		DB:      db,
+		Store:   store,
+		Auth:    testConfig.Auth,
+		Fines:   testConfig.Fines.Policy(),
+		Health:  controllers.NewHealthController(db, logging.Discard()),
+		Metrics: metrics.New(db, store.Records(), logging.Discard()),
+		Log:     logging.Discard(),
+	}, routes.Auth{Session: checkAuth, Patron: checkAuth, Staff: checkAuth})
+	spec := fetchSpec(t, router)
+
+	var documented []string
+	for path, item := range spec["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			documented = append(documented, strings.ToUpper(method)+" "+pathParam.ReplaceAllString(path, ":$1"))
+		}
+	}
+	var registered []string
+	for _, route := range router.Routes() {
+		registered = append(registered, route.Method+" "+route.Path)
+	}
+	assert.ElementsMatch(t, registered, documented)
+}
+
+func TestOpenAPISchemasFromModels(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	spec := fetchSpec(t, SetupMockRouter(db))
+	assert.Equal(t, "3.0.3", spec["openapi"])
+
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	property := func(schema, name string) map[string]any {
+		require.Contains(t, schemas, schema)
+		properties := schemas[schema].(map[string]any)["properties"].(map[string]any)
+		require.Contains(t, properties, name, schema)
+		return properties[name].(map[string]any)
+	}
+
+	assert.Equal(t, "array", property("BookIDsPayload", "ids")["type"])
+	assert.Equal(t, "integer", property("RecordSearchRequest", "page")["type"])
+	assert.Equal(t, "integer", property("RecordSearchRequest", "page_size")["type"])
+	assert.Equal(t, "date-time", property("RecordResponse", "due_at")["format"])
+	assert.Equal(t, true, property("RecordResponse", "returned_at")["nullable"])
+	assert.Equal(t, []any{"patron", "librarian", "admin"}, property("UserRoleRequest", "role")["enum"])
+	assert.Equal(t, float64(1), property("BookCopiesPayload", "count")["minimum"])
+	assert.ElementsMatch(t, []any{"username", "password", "nickname"}, schemas["SignUpPayload"].(map[string]any)["required"])
+	assert.Equal(t, "string", property("Error", "code")["type"])
+
+	// Every reference points at a schema that exists
+	body, _ := json.Marshal(spec)
+	for _, ref := range regexp.MustCompile(`"\$ref":"#/components/schemas/(\w+)"`).FindAllStringSubmatch(string(body), -1) {
+		assert.Contains(t, schemas, ref[1])
+	}
+
+	borrow := spec["paths"].(map[string]any)["/book/borrow"].(map[string]any)["post"].(map[string]any)
+	assert.NotEmpty(t, borrow["security"])
+	list := spec["paths"].(map[string]any)["/book/list"].(map[string]any)["post"].(map[string]any)
+	assert.Empty(t, list["security"])
+}
+
+func TestDocsPage(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	req, _ := http.NewRequest("GET", "/docs", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "SwaggerUIBundle")
+	assert.Contains(t, w.Body.String(), "/openapi.json")
+}
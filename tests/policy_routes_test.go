@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBorrowBooksLoanLimitReached(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockPolicyDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{1}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/book/borrow", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestExtendRecordsRenewalLimitReached(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockPolicyDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids = []int{3}
+	requestBody, _ := json.Marshal(map[string][]int{
+		"ids": ids,
+	})
+	req, _ := http.NewRequest("POST", "/record/extend", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestSavePolicyDuplicateScope(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockPolicyDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"patron_category": "standard",
+		"loan_days":       21,
+	})
+	req, _ := http.NewRequest("POST", "/policy/save", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"database/sql"
+	"encoding/json"
+	"library/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func auditEntries(t *testing.T, db *gorm.DB) []models.AuditEntry {
+	var entries []models.AuditEntry
+	require.NoError(t, db.Order("id").Find(&entries).Error)
+	return entries
+}
+
+func updateProfile(t *testing.T, router *gin.Engine, body map[string]any) models.ProfileResponse {
+	w := serve(router, "PATCH", "/api/v1/me", body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		User models.ProfileResponse `json:"user"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	return response.User
+}
+
+func TestUpdateProfile(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockUserDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	defer PrepareMockUserDB(db)
+	router := SetupMockRouter(db)
+
+	w := serve(router, "GET", "/api/v1/me", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user": {"id": 1, "username": "mock", "nickname": "Mock", "email": "", "phone": "", "role": "patron", "category": "standard"}}`, w.Body.String())
+
+	profile := updateProfile(t, router, map[string]any{"email": " Reader@Example.COM ", "phone": "+1 (555) 010-9999"})
+	assert.Equal(t, "Reader@example.com", profile.Email)
+	assert.Equal(t, "+15550109999", profile.Phone)
+	assert.Equal(t, "Mock", profile.Nickname)
+
+	var user models.User
+	require.NoError(t, db.First(&user, 1).Error)
+	assert.Equal(t, "Reader@example.com", user.Email)
+	assert.Equal(t, "+15550109999", user.Phone)
+
+	// Sending the same values again changes nothing and is not audited
+	updateProfile(t, router, map[string]any{"email": "Reader@example.com", "nickname": "Mock"})
+	profile = updateProfile(t, router, map[string]any{"nickname": " Reader ", "email": ""})
+	assert.Equal(t, "Reader", profile.Nickname)
+	assert.Empty(t, profile.Email)
+	assert.Equal(t, "+15550109999", profile.Phone)
+
+	entries := auditEntries(t, db)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "profile_updated", entries[0].Action)
+	assert.Equal(t, "email,phone", entries[0].Fields)
+	assert.Equal(t, uint(1), entries[0].UserID)
+	assert.Equal(t, uint(1), entries[0].ActorID)
+	assert.NotEmpty(t, entries[0].RequestID)
+	assert.Equal(t, "nickname,email", entries[1].Fields)
+
+	tests := []struct {
+		body map[string]any
+		code string
+	}{
+		{map[string]any{"nickname": "  "}, "INVALID_PAYLOAD"},
+		{map[string]any{"email": "not-an-email"}, "INVALID_EMAIL"},
+		{map[string]any{"email": "Reader <reader@example.com>"}, "INVALID_EMAIL"},
+		{map[string]any{"phone": "call me"}, "INVALID_PHONE"},
+		{map[string]any{"phone": "123"}, "INVALID_PHONE"},
+	}
+	for _, tt := range tests {
+		w := serve(router, "PATCH", "/api/v1/me", tt.body)
+		assert.Equal(t, tt.code, decodeError(t, w).Error.Code, tt.body)
+	}
+	assert.Len(t, auditEntries(t, db), 2)
+}
+
+func TestChangePassword(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockUserDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	defer PrepareMockUserDB(db)
+	router := SetupMockRouter(db)
+
+	expires := time.Now().Add(time.Hour)
+	sessions := []models.Session{{UserID: 1, ExpiresAt: expires}, {UserID: 2, ExpiresAt: expires}}
+	require.NoError(t, db.Omit("User").Create(&sessions).Error)
+
+	w := serve(router, "POST", "/api/v1/me/password", map[string]any{"old_password": "wrong", "new_password": "correct horse"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "WRONG_PASSWORD", decodeError(t, w).Error.Code)
+	w = serve(router, "POST", "/api/v1/me/password", map[string]any{"old_password": "admin", "new_password": "short"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Empty(t, auditEntries(t, db))
+
+	w = serve(router, "POST", "/api/v1/me/password", map[string]any{"old_password": "admin", "new_password": "correct horse"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var user models.User
+	require.NoError(t, db.First(&user, 1).Error)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("correct horse")))
+
+	// Sessions opened with the old password are signed out, other users keep theirs
+	require.NoError(t, db.Find(&sessions).Error)
+	assert.NotNil(t, sessions[0].RevokedAt)
+	assert.Nil(t, sessions[1].RevokedAt)
+
+	entries := auditEntries(t, db)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "password_changed", entries[0].Action)
+	assert.Equal(t, "password", entries[0].Fields)
+
+	w = serve(router, "POST", "/user/signin", map[string]any{"username": "mock", "password": "correct horse"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(router, "POST", "/user/signin", map[string]any{"username": "mock", "password": "admin"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockUserDB(db)
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	defer PrepareMockUserDB(db)
+	router := SetupMockRouter(db)
+
+	w := serve(router, "DELETE", "/api/v1/me", map[string]any{"password": "wrong"})
+	assert.Equal(t, "WRONG_PASSWORD", decodeError(t, w).Error.Code)
+
+	// User 1 still has books out
+	w = serve(router, "DELETE", "/api/v1/me", map[string]any{"password": "admin"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "USERS_HAVE_LOANS", decodeError(t, w).Error.Code)
+	assert.Empty(t, auditEntries(t, db))
+
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", 1).Update("role", models.RoleAdmin).Error)
+	w = serve(router, "DELETE", "/api/v1/me", map[string]any{"password": "admin"})
+	assert.Equal(t, "SELF_ACTION_FORBIDDEN", decodeError(t, w).Error.Code)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", 1).Update("role", models.RolePatron).Error)
+
+	require.NoError(t, db.Model(&models.Record{}).Where("user_id = ?", 1).Update("is_closed", true).Error)
+	w = serve(router, "DELETE", "/api/v1/me", map[string]any{"password": "admin"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var count int64
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", 1).Count(&count).Error)
+	assert.Zero(t, count)
+
+	entries := auditEntries(t, db)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "account_deleted", entries[0].Action)
+	assert.Equal(t, uint(1), entries[0].UserID)
+
+	w = serve(router, "GET", "/api/v1/me", nil)
+	assert.Equal(t, "USER_NOT_FOUND", decodeError(t, w).Error.Code)
+}
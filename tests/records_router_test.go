@@ -14,8 +14,10 @@ import (
 )
 
 type RecordListResponse struct {
-	Records []models.RecordResponse `json:"records"`
-	Total   int                     `json:"total"`
+	Records    []models.RecordResponse `json:"records"`
+	Total      int                     `json:"total"`
+	NextCursor string                  `json:"next_cursor"`
+	HasMore    bool                    `json:"has_more"`
 }
 
 var mockRecordListExpectedReturn = RecordListResponse{
@@ -71,11 +73,45 @@ func TestGetRecordList(t *testing.T) {
 		assert.Equal(t, mockRecordListExpectedReturn.Records[index].Title, recordListResponse.Records[index].Title)
 		assert.Equal(t, mockRecordListExpectedReturn.Records[index].Name, recordListResponse.Records[index].Name)
 		assert.Equal(t, mockRecordListExpectedReturn.Records[index].ID, recordListResponse.Records[index].ID)
-		assert.Equal(t, mockRecordListExpectedReturn.Records[index].DueAt, recordListResponse.Records[index].DueAt)
+		assert.WithinDuration(t, mockRecordListExpectedReturn.Records[index].DueAt, recordListResponse.Records[index].DueAt, 0)
 		assert.Equal(t, mockRecordListExpectedReturn.Records[index].Status, recordListResponse.Records[index].Status)
 	}
 }
 
+func TestGetRecordListCursor(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	var ids []uint
+	query := "/api/v1/me/loans?page_size=1"
+	for pages := 0; pages < 5; pages++ {
+		w := serve(router, "GET", query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var recordListResponse RecordListResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&recordListResponse))
+		assert.Equal(t, 3, recordListResponse.Total)
+		require.Len(t, recordListResponse.Records, 1)
+		ids = append(ids, recordListResponse.Records[0].ID)
+		if !recordListResponse.HasMore {
+			break
+		}
+		query = "/api/v1/me/loans?page_size=1&cursor=" + recordListResponse.NextCursor
+	}
+	assert.Equal(t, []uint{1, 3, 4}, ids)
+
+	// The title filter counts only the records it matches
+	w := serve(router, "GET", "/api/v1/me/loans?title=book+1", nil)
+	var recordListResponse RecordListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&recordListResponse))
+	assert.Equal(t, 2, recordListResponse.Total)
+	assert.False(t, recordListResponse.HasMore)
+
+	w = serve(router, "GET", "/api/v1/me/loans?cursor=e30", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestExtendRecordsOtherUserRecord(t *testing.T) {
 	db := SetupMockDB()
 	PrepareMockRecordDB(db)
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"library/logging"
+	"library/middlewares"
+	"library/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func SetupRoleRouter(role string) *gin.Engine {
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler(logging.Discard()))
+	router.GET("/staff",
+		func(c *gin.Context) {
+			c.Set("user", models.UserResponse{ID: 1, Nickname: "Test", Role: role})
+		},
+		middlewares.RequireRole(models.RoleLibrarian, models.RoleAdmin),
+		func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "ok"})
+		})
+	return router
+}
+
+func TestRequireRolePatronForbidden(t *testing.T) {
+	router := SetupRoleRouter(models.RolePatron)
+
+	req, _ := http.NewRequest("GET", "/staff", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequireRoleLibrarianAllowed(t *testing.T) {
+	router := SetupRoleRouter(models.RoleLibrarian)
+
+	req, _ := http.NewRequest("GET", "/staff", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"database/sql"
+	"encoding/json"
+	"library/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// prepareSearchCatalog adds titles 4 to 6 to the mock catalog: two editions
+// of The Hobbit by Tolkien and a book about him. The schema comes from the
+// migrations, which set up Postgres search, so the assertions hold on both
+// engines.
+func prepareSearchCatalog(t *testing.T, db *gorm.DB) {
+	PrepareMigratedBookDB(db)
+
+	tolkien := models.Author{Name: "J. R. R. Tolkien"}
+	require.NoError(t, db.Create(&tolkien).Error)
+	fantasy := models.Subject{Heading: "Fantasy"}
+	require.NoError(t, db.Create(&fantasy).Error)
+
+	bookTypes := []models.BookType{
+		{
+			Title: "The Hobbit", Language: "en", PublicationYear: 1937,
+			Description: "In a hole in the ground there lived a hobbit. Not a nasty, dirty, wet hole.",
+			Authors:     []models.Author{tolkien}, Subjects: []models.Subject{fantasy},
+		},
+		{
+			Title: "Der Hobbit", Language: "de", PublicationYear: 1957,
+			Authors: []models.Author{tolkien}, Subjects: []models.Subject{fantasy},
+		},
+		{
+			Title: "Worlds of Wonder", Language: "en", PublicationYear: 2001,
+			Authors:  []models.Author{{Name: "Anne Writer"}},
+			Subjects: []models.Subject{{Heading: "Tolkien studies"}},
+		},
+	}
+	require.NoError(t, db.Create(&bookTypes).Error)
+	require.NoError(t, db.Create(&[]models.Book{
+		{BookTypeID: 4, Status: 1},
+		{BookTypeID: 5, Status: 2},
+		{BookTypeID: 6, Status: 1},
+	}).Error)
+}
+
+func searchCatalog(t *testing.T, router *gin.Engine, query string) models.SearchResponse {
+	w := serve(router, "GET", "/api/v1/search?"+query, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response models.SearchResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	return response
+}
+
+func resultIDs(response models.SearchResponse) []uint {
+	var ids []uint
+	for _, hit := range response.Results {
+		ids = append(ids, hit.Book.ID)
+	}
+	return ids
+}
+
+func TestSearchRanksAndHighlights(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	prepareSearchCatalog(t, db)
+	router := SetupMockRouter(db)
+
+	response := searchCatalog(t, router, "q=hobbit")
+	require.Equal(t, 2, response.Total)
+	assert.Equal(t, []uint{4, 5}, resultIDs(response))
+	hit := response.Results[0]
+	assert.Greater(t, hit.Score, 0.0)
+	assert.Equal(t, "The <mark>Hobbit</mark>", hit.Highlights["title"])
+	assert.Contains(t, hit.Highlights["description"], "lived a <mark>hobbit</mark>")
+	assert.Equal(t, []string{"J. R. R. Tolkien"}, hit.Book.Authors)
+
+	// A title match outranks an author match, which outranks a subject match
+	response = searchCatalog(t, router, "q=tolkien")
+	assert.Equal(t, []uint{4, 5, 6}, resultIDs(response))
+	assert.Equal(t, "J. R. R. <mark>Tolkien</mark>", response.Results[0].Highlights["authors"])
+	assert.Equal(t, "<mark>Tolkien</mark> studies", response.Results[2].Highlights["subjects"])
+	assert.Greater(t, response.Results[0].Score, response.Results[2].Score)
+
+	// Every word has to match
+	response = searchCatalog(t, router, "q=hobbit+wonder")
+	assert.Equal(t, 0, response.Total)
+	assert.Empty(t, response.Results)
+}
+
+func TestSearchToleratesTypos(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	prepareSearchCatalog(t, db)
+	router := SetupMockRouter(db)
+
+	response := searchCatalog(t, router, "q=hobit+tolkin")
+	assert.Equal(t, []uint{4, 5}, resultIDs(response))
+	assert.Equal(t, "The <mark>Hobbit</mark>", response.Results[0].Highlights["title"])
+
+	// Short words must match exactly
+	response = searchCatalog(t, router, "q=dar")
+	assert.Equal(t, 0, response.Total)
+}
+
+func TestSearchFacets(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	prepareSearchCatalog(t, db)
+	router := SetupMockRouter(db)
+
+	response := searchCatalog(t, router, "q=tolkien")
+	assert.Equal(t, []models.FacetCount{{Value: "en", Count: 2}, {Value: "de", Count: 1}}, response.Facets.Languages)
+	assert.Equal(t, []models.YearFacet{{From: 1930, To: 1939, Count: 1}, {From: 1950, To: 1959, Count: 1}, {From: 2000, To: 2009, Count: 1}}, response.Facets.Years)
+	assert.Equal(t, []models.FacetCount{{Value: "available", Count: 2}, {Value: "unavailable", Count: 1}}, response.Facets.Availability)
+
+	// A filter narrows the results and the other facets, not its own facet
+	response = searchCatalog(t, router, "q=tolkien&language=DE")
+	assert.Equal(t, []uint{5}, resultIDs(response))
+	assert.Len(t, response.Facets.Languages, 2)
+	assert.Equal(t, []models.FacetCount{{Value: "unavailable", Count: 1}}, response.Facets.Availability)
+
+	response = searchCatalog(t, router, "q=tolkien&available=true&year_to=1999")
+	assert.Equal(t, []uint{4}, resultIDs(response))
+
+	response = searchCatalog(t, router, "q=tolkien&page=1&page_size=2")
+	assert.Equal(t, 3, response.Total)
+	assert.Equal(t, []uint{6}, resultIDs(response))
+}
+
+func TestSearchSeesCatalogChanges(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	prepareSearchCatalog(t, db)
+	router := SetupMockRouter(db)
+
+	assert.Equal(t, 0, searchCatalog(t, router, "q=silmarillion").Total)
+	require.NoError(t, db.Create(&models.BookType{Title: "The Silmarillion"}).Error)
+	assert.Equal(t, 1, searchCatalog(t, router, "q=silmarillion").Total)
+
+	require.NoError(t, db.Delete(&models.BookType{}, 4).Error)
+	assert.Equal(t, []uint{5}, resultIDs(searchCatalog(t, router, "q=hobbit")))
+}
+
+func TestSearchSeesRenames(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	prepareSearchCatalog(t, db)
+	router := SetupMockRouter(db)
+
+	assert.Equal(t, 0, searchCatalog(t, router, "q=ronald").Total)
+	require.NoError(t, db.Model(&models.Author{}).Where("name = ?", "J. R. R. Tolkien").Update("name", "John Ronald Reuel Tolkien").Error)
+	assert.Equal(t, []uint{4, 5}, resultIDs(searchCatalog(t, router, "q=ronald")))
+
+	require.NoError(t, db.Model(&models.Subject{}).Where("heading = ?", "Tolkien studies").Update("heading", "Inklings studies").Error)
+	assert.Equal(t, []uint{6}, resultIDs(searchCatalog(t, router, "q=inklings")))
+	assert.Equal(t, []uint{4, 5}, resultIDs(searchCatalog(t, router, "q=tolkien")))
+}
+
+func TestSearchRequiresQuery(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := serve(router, "GET", "/api/v1/search", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func signIn(t *testing.T, router *gin.Engine) tokenPair {
+	requestBody, _ := json.Marshal(map[string]string{
+		"username": "mock",
+		"password": "admin",
+	})
+	req, _ := http.NewRequest("POST", "/user/signin", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var tokens tokenPair
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	require.NotEmpty(t, tokens.Token)
+	require.NotEmpty(t, tokens.RefreshToken)
+	return tokens
+}
+
+func refresh(router *gin.Engine, refreshToken string) *httptest.ResponseRecorder {
+	requestBody, _ := json.Marshal(map[string]string{
+		"refresh_token": refreshToken,
+	})
+	req, _ := http.NewRequest("POST", "/user/refresh", bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func authorized(router *gin.Engine, path, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString("{}"))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockSessionDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	tokens := signIn(t, router)
+
+	w := refresh(router, tokens.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rotated tokenPair
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.NotEqual(t, tokens.RefreshToken, rotated.RefreshToken)
+
+	assert.Equal(t, http.StatusOK, authorized(router, "/user/sessions", rotated.Token).Code)
+}
+
+func TestRefreshTokenReuseRevokesSessions(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockSessionDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	tokens := signIn(t, router)
+	other := signIn(t, router)
+
+	w := refresh(router, tokens.RefreshToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var rotated tokenPair
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+
+	// Replaying the old token locks out every session of the user
+	assert.Equal(t, http.StatusUnauthorized, refresh(router, tokens.RefreshToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(router, rotated.RefreshToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, authorized(router, "/user/sessions", other.Token).Code)
+}
+
+func TestRefreshTokenUnknown(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockSessionDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	assert.Equal(t, http.StatusUnauthorized, refresh(router, "not-a-token").Code)
+}
+
+func TestLogout(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockSessionDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	tokens := signIn(t, router)
+	other := signIn(t, router)
+
+	assert.Equal(t, http.StatusOK, authorized(router, "/user/logout", tokens.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, authorized(router, "/user/sessions", tokens.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(router, tokens.RefreshToken).Code)
+
+	// Other sessions are unaffected
+	assert.Equal(t, http.StatusOK, authorized(router, "/user/sessions", other.Token).Code)
+}
+
+func TestLogoutAll(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockSessionDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	tokens := signIn(t, router)
+	other := signIn(t, router)
+
+	assert.Equal(t, http.StatusOK, authorized(router, "/user/logout-all", tokens.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, authorized(router, "/user/sessions", tokens.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, authorized(router, "/user/sessions", other.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(router, other.RefreshToken).Code)
+}
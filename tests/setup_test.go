@@ -2,20 +2,44 @@ package tests
 
 import (
 	"database/sql"
+	"library/config"
 	"library/controllers"
 	"library/initializers"
+	"library/logging"
+	"library/metrics"
+	"library/middlewares"
+	"library/migrations"
 	"library/models"
+	"library/repositories"
+	"library/routes"
 	"log"
+	"log/slog"
+	"os"
 	"testing"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// SetupMockDB initializes a mock PostgreSQL database using pgxmock
+// testConfig is the default config with the settings a real deployment must provide
+var testConfig = func() config.Config {
+	cfg := config.Default()
+	cfg.Database = config.DatabaseConfig{Driver: "sqlite", URL: "file:library_test?mode=memory&cache=shared"}
+	cfg.Auth.Secret = "test-secret"
+	return cfg
+}()
+
+// SetupMockDB opens the test database, an in-memory SQLite database shared by
+// every test unless TEST_DB_DRIVER and TEST_DB_URL point somewhere else
 func SetupMockDB() *gorm.DB {
-	DB, err := gorm.Open(postgres.Open("host=localhost user=postgres password=admin dbname=library_test port=5432 sslmode=disable"), &gorm.Config{})
+	driver, dsn := os.Getenv("TEST_DB_DRIVER"), os.Getenv("TEST_DB_URL")
+	if driver == "" {
+		driver = "sqlite"
+	}
+	if dsn == "" && driver == "sqlite" {
+		dsn = testConfig.Database.URL
+	}
+	DB, err := initializers.OpenDB(driver, dsn)
 	if err != nil {
 		log.Fatal("Failed to connect to DB:", err)
 	}
@@ -23,36 +47,48 @@ func SetupMockDB() *gorm.DB {
 }
 
 func SetupMockRouter(db *gorm.DB) *gin.Engine {
-	router := gin.Default()
-
-	userController := controllers.NewUserController(db)
-	userRouter := router.Group("/user")
-	{
-		userRouter.POST("/signup", userController.CreateUser)
-		userRouter.POST("/signin", userController.SignIn)
-		userRouter.GET("/info", MockCheckAuth, userController.GetUserInfo)
-	}
+	return SetupMockRouterWithLogger(db, logging.Discard())
+}
 
-	bookController := controllers.NewBookController(db)
-	bookRouter := router.Group("/book")
-	{
-		bookRouter.POST("/list", bookController.GetBookList)
-		bookRouter.POST("/borrow", MockCheckAuth, bookController.BorrowBooks)
-	}
+// SetupMockRouterWithLogger builds the router with the same logging
+// middleware as main, writing to logger
+func SetupMockRouterWithLogger(db *gorm.DB, logger *slog.Logger) *gin.Engine {
+	return SetupMockRouterWithAuth(db, logger, mockAuth(db))
+}
 
-	recordController := controllers.NewRecordController(db)
-	recordRouter := router.Group("/record")
-	{
-		recordRouter.POST("/list", MockCheckAuth, recordController.GetRecordList)
-		recordRouter.POST("/extend", MockCheckAuth, recordController.ExtendRecords)
-		recordRouter.POST("/return", MockCheckAuth, recordController.ReturnRecords)
-	}
+// SetupMockRouterWithAuth builds the router with auth in front of each route group
+func SetupMockRouterWithAuth(db *gorm.DB, logger *slog.Logger, auth routes.Auth) *gin.Engine {
+	store := repositories.NewStore(db)
+	testMetrics := metrics.New(db, store.Records(), logger)
+
+	router := gin.New()
+	router.Use(logging.RequestID(), logging.AccessLog(logger), logging.Recovery(logger), testMetrics.Middleware(), middlewares.ErrorHandler(logger))
+	router.NoRoute(middlewares.NoRoute)
+
+	routes.RegisterRoutes(router, routes.Deps{
+		DB:      db,
+		Store:   store,
+		Auth:    testConfig.Auth,
+		Fines:   testConfig.Fines.Policy(),
+		Health:  controllers.NewHealthController(db, logger),
+		Metrics: testMetrics,
+		Log:     logger,
+	}, auth)
 	return router
 }
 
+// mockAuth signs requests in as the mock patron, and staff requests as the
+// mock admin; session routes check the real token
+func mockAuth(db *gorm.DB) routes.Auth {
+	return routes.Auth{
+		Session: middlewares.CheckAuth(db, testConfig.Auth),
+		Patron:  MockCheckAuth,
+		Staff:   MockStaffCheckAuth,
+	}
+}
+
 // TestMain runs before any test starts
 func TestMain(m *testing.M) {
-	initializers.GetEnvs()
 	gin.SetMode(gin.TestMode)
 	db := SetupMockDB()
 	PrepareMockUserDB(db)
@@ -64,32 +100,98 @@ func MockCheckAuth(c *gin.Context) {
 	var user = models.UserResponse{
 		ID:       1,
 		Nickname: "Test",
+		Role:     models.RolePatron,
+	}
+	c.Set("user", user)
+	c.Set(logging.UserIDKey, user.ID)
+}
+
+func MockStaffCheckAuth(c *gin.Context) {
+	var user = models.UserResponse{
+		ID:       2,
+		Nickname: "Staff",
+		Role:     models.RoleAdmin,
 	}
 	c.Set("user", user)
+	c.Set(logging.UserIDKey, user.ID)
 }
 
 func PrepareMockUserDB(db *gorm.DB) {
-	db.Migrator().DropTable(&models.User{})
-	db.Migrator().AutoMigrate(&models.User{})
+	PrepareMockSessionDB(db)
+	db.Migrator().DropTable(&models.User{}, &models.AuditEntry{})
+	db.Migrator().AutoMigrate(&models.User{}, &models.AuditEntry{})
 	db.Save(&MockUser)
 
 }
 func PrepareMockBookDB(db *gorm.DB) {
+	db.Migrator().DropTable(&models.LoanPolicy{})
+	db.Migrator().AutoMigrate(&models.LoanPolicy{})
+	db.Migrator().DropTable(&models.Fine{}, &models.FinePayment{})
+	db.Migrator().DropTable(&models.Hold{})
 	db.Migrator().DropTable(&models.Book{})
+	db.Migrator().DropTable("book_type_authors", "book_type_subjects")
 	db.Migrator().DropTable(&models.BookType{})
+	db.Migrator().DropTable(&models.Author{}, &models.Subject{})
+	db.Migrator().AutoMigrate(&models.Fine{}, &models.FinePayment{})
+	db.Migrator().AutoMigrate(&models.Hold{})
 	db.Migrator().AutoMigrate(&models.Book{})
+	db.Migrator().AutoMigrate(&models.Author{}, &models.Subject{})
 	db.Migrator().AutoMigrate(&models.BookType{})
 	db.Save(&MockBookType)
 	db.Save(&MockBook)
 }
 func PrepareMockRecordDB(db *gorm.DB) {
+	db.Migrator().DropTable(&models.LoanPolicy{})
+	db.Migrator().AutoMigrate(&models.LoanPolicy{})
+	db.Migrator().DropTable(&models.Fine{}, &models.FinePayment{})
+	db.Migrator().DropTable(&models.Hold{})
 	db.Migrator().DropTable(&models.Record{})
 	db.Migrator().DropTable(&models.Book{})
+	db.Migrator().DropTable("book_type_authors", "book_type_subjects")
 	db.Migrator().DropTable(&models.BookType{})
+	db.Migrator().DropTable(&models.Author{}, &models.Subject{})
+	db.Migrator().AutoMigrate(&models.Fine{}, &models.FinePayment{})
+	db.Migrator().AutoMigrate(&models.Hold{})
 	db.Migrator().AutoMigrate(&models.Record{})
 	db.Migrator().AutoMigrate(&models.Book{})
+	db.Migrator().AutoMigrate(&models.Author{}, &models.Subject{})
 	db.Migrator().AutoMigrate(&models.BookType{})
 	db.Save(&MockBookType)
 	db.Save(&MockBook)
 	db.Save(&MockRecord)
 }
+func PrepareMockHoldDB(db *gorm.DB) {
+	PrepareMockRecordDB(db)
+	db.Save(&MockHold)
+}
+func PrepareMockFineDB(db *gorm.DB) {
+	PrepareMockRecordDB(db)
+	db.Save(&MockFine)
+}
+func PrepareMockPolicyDB(db *gorm.DB) {
+	PrepareMockRecordDB(db)
+	db.Save(&MockLoanPolicy)
+}
+func PrepareMockSessionDB(db *gorm.DB) {
+	db.Migrator().DropTable(&models.RefreshToken{}, &models.Session{})
+	db.Migrator().AutoMigrate(&models.Session{}, &models.RefreshToken{})
+}
+
+// PrepareMigratedBookDB builds every table through the migrations rather than
+// AutoMigrate, for features that live in them like Postgres search, and adds
+// the mock users, titles and copies
+func PrepareMigratedBookDB(db *gorm.DB) {
+	db.Migrator().DropTable(
+		&migrations.SchemaMigration{},
+		&models.RefreshToken{}, &models.Session{}, &models.AuditEntry{},
+		&models.FinePayment{}, &models.Fine{}, &models.Hold{}, &models.Record{}, &models.Book{},
+		"book_type_authors", "book_type_subjects", &models.BookType{},
+		&models.Author{}, &models.Subject{}, &models.LoanPolicy{}, &models.User{},
+	)
+	if _, err := migrations.Up(db, 0); err != nil {
+		log.Fatal("Failed to migrate DB:", err)
+	}
+	db.Save(&MockUser)
+	db.Save(&MockBookType)
+	db.Save(&MockBook)
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"library/logging"
+	"library/models"
+	"library/workers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TrashListResponse struct {
+	Items []models.TrashItem `json:"items"`
+	Total int                `json:"total"`
+}
+
+func postJSON(router *gin.Engine, path string, body interface{}) *httptest.ResponseRecorder {
+	requestBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(requestBody))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDeleteAndRestoreBookType(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := postJSON(router, "/catalog/create", map[string]interface{}{"title": "Short Lived", "copies": 2})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = postJSON(router, "/catalog/delete", map[string][]int{"ids": {4}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var bookListResponse BookListResponse
+	w = postJSON(router, "/book/list", map[string]interface{}{"title": "Short Lived", "page_size": 10})
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bookListResponse))
+	assert.Empty(t, bookListResponse.Books)
+
+	var trashListResponse TrashListResponse
+	w = postJSON(router, "/trash/list", map[string]interface{}{"type": "titles", "page_size": 10})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &trashListResponse))
+	require.Len(t, trashListResponse.Items, 1)
+	assert.Equal(t, "Short Lived", trashListResponse.Items[0].Label)
+
+	w = postJSON(router, "/trash/restore", map[string]interface{}{"type": "titles", "ids": []int{4}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The copies deleted with the title are back on the shelf
+	w = postJSON(router, "/book/list", map[string]interface{}{"title": "Short Lived", "page_size": 10})
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bookListResponse))
+	require.Len(t, bookListResponse.Books, 1)
+	assert.Equal(t, 2, bookListResponse.Books[0].AvailableCount)
+}
+
+func TestRestoreCopyOfDeletedTitle(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := postJSON(router, "/catalog/create", map[string]interface{}{"title": "Short Lived", "copies": 1})
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = postJSON(router, "/catalog/delete", map[string][]int{"ids": {4}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = postJSON(router, "/trash/restore", map[string]interface{}{"type": "copies", "ids": []int{7}})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestRestoreNotDeleted(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockBookDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := postJSON(router, "/trash/restore", map[string]interface{}{"type": "titles", "ids": []int{1}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteUserWithOpenRecords(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := postJSON(router, "/user/delete", map[string][]int{"ids": {1}})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestDeleteAndRestoreUser(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	defer PrepareMockUserDB(db)
+	router := SetupMockRouter(db)
+
+	user := models.User{Username: "leaving", Password: "x", Nickname: "Leaving"}
+	require.NoError(t, db.Create(&user).Error)
+
+	w := postJSON(router, "/user/delete", map[string][]uint{"ids": {user.ID}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var remaining int64
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Count(&remaining).Error)
+	assert.Equal(t, int64(0), remaining)
+
+	// The username stays taken until the account is purged
+	w = postJSON(router, "/user/signup", map[string]string{"username": "leaving", "password": "x", "nickname": "Again"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = postJSON(router, "/trash/restore", map[string]interface{}{"type": "users", "ids": []uint{user.ID}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Count(&remaining).Error)
+	assert.Equal(t, int64(1), remaining)
+}
+
+func TestPurgeDeletedRows(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+
+	now := time.Now()
+	old := models.BookType{Title: "Long Gone"}
+	recent := models.BookType{Title: "Just Gone"}
+	require.NoError(t, db.Create(&old).Error)
+	require.NoError(t, db.Create(&recent).Error)
+	require.NoError(t, db.Model(&old).Update("deleted_at", now.AddDate(0, 0, -100)).Error)
+	require.NoError(t, db.Model(&recent).Update("deleted_at", now.AddDate(0, 0, -1)).Error)
+
+	purged, err := workers.NewPurgeWorker(db, 90*24*time.Hour, time.Hour, logging.Discard()).Purge(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged["book_types"])
+
+	var left []models.BookType
+	require.NoError(t, db.Unscoped().Where("title IN ?", []string{"Long Gone", "Just Gone"}).Find(&left).Error)
+	require.Len(t, left, 1)
+	assert.Equal(t, "Just Gone", left[0].Title)
+}
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateUserRepeatedUsername(t *testing.T) {
@@ -95,3 +98,69 @@ func TestGetInfo(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestUpdateUserRoleOwnRole(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"user_id": 2,
+		"role":    "patron",
+	})
+
+	req, _ := http.NewRequest("POST", "/user/role", bytes.NewBuffer(requestBody))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUpdateUserRoleSuccess(t *testing.T) {
+	db := SetupMockDB()
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"user_id": 1,
+		"role":    "librarian",
+	})
+
+	req, _ := http.NewRequest("POST", "/user/role", bytes.NewBuffer(requestBody))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// Responses never carry the password hash, nor a borrower's contact details
+func TestResponsesHideUserSecrets(t *testing.T) {
+	db := SetupMockDB()
+	PrepareMockUserDB(db)
+	PrepareMockRecordDB(db)
+	defer db.ConnPool.(*sql.DB).Close()
+	router := SetupMockRouter(db)
+
+	w := serve(router, "POST", "/user/signup", map[string]string{"username": "secretive", "password": "admin123", "nickname": "Secretive"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var signup struct {
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&signup))
+	assert.Equal(t, "secretive", signup.Data["username"])
+	assert.NotContains(t, signup.Data, "password")
+
+	w = serve(router, "POST", "/book/borrow", map[string][]int{"ids": {1}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "$2a$")
+	var borrow struct {
+		Data []map[string]any `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&borrow))
+	require.Len(t, borrow.Data, 1)
+	borrower, ok := borrow.Data[0]["User"].(map[string]any)
+	require.True(t, ok)
+	assert.ElementsMatch(t, []string{"id", "nickname", "role"}, slices.Collect(maps.Keys(borrower)))
+}
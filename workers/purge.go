@@ -0,0 +1,125 @@
+// Package workers holds the background jobs that run alongside the API.
+package workers
+
+import (
+	"context"
+	"library/logging"
+	"library/models"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PurgeWorker permanently removes soft deleted rows once they are older
+// than the retention period. Rows still referenced by history that has to
+// be kept, such as records with fines, wait until that history goes too.
+type PurgeWorker struct {
+	DB        *gorm.DB
+	Retention time.Duration
+	Interval  time.Duration
+	Log       *slog.Logger
+}
+
+// Constructor function to create a new PurgeWorker
+func NewPurgeWorker(db *gorm.DB, retention, interval time.Duration, logger *slog.Logger) *PurgeWorker {
+	return &PurgeWorker{DB: db, Retention: retention, Interval: interval, Log: logger}
+}
+
+// Run purges once immediately and then every Interval until ctx is done
+func (w *PurgeWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Purge(time.Now()); err != nil {
+			w.Log.ErrorContext(ctx, "Failed to purge deleted rows", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes rows deleted before now minus the retention period and
+// reports how many rows it removed per table
+func (w *PurgeWorker) Purge(now time.Time) (map[string]int64, error) {
+	cutoff := now.Add(-w.Retention)
+	purged := map[string]int64{}
+
+	err := w.DB.Transaction(func(tx *gorm.DB) error {
+		expired := func(table string) *gorm.DB {
+			return tx.Unscoped().Table(table).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		}
+
+		// Records with fines are kept as the fine's history
+		result := tx.Unscoped().
+			Where("id IN (?)", expired("records")).
+			Where("NOT EXISTS (SELECT 1 FROM fines WHERE fines.record_id = records.id)").
+			Delete(&models.Record{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged["records"] = result.RowsAffected
+
+		// Copies nobody borrowed or holds any more
+		result = tx.Unscoped().
+			Where("id IN (?)", expired("books")).
+			Where("NOT EXISTS (SELECT 1 FROM records WHERE records.book_id = books.id)").
+			Where("NOT EXISTS (SELECT 1 FROM holds WHERE holds.book_id = books.id)").
+			Delete(&models.Book{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged["books"] = result.RowsAffected
+
+		// Titles once all their copies are gone, taking closed holds and catalog links along
+		titles := expired("book_types").
+			Where("NOT EXISTS (SELECT 1 FROM books WHERE books.book_type_id = book_types.id)").
+			Where("NOT EXISTS (SELECT 1 FROM holds WHERE holds.book_type_id = book_types.id AND holds.status IN (1, 2))")
+		if err := tx.Unscoped().Where("book_type_id IN (?)", titles).Delete(&models.Hold{}).Error; err != nil {
+			return err
+		}
+		for _, joinTable := range []string{"book_type_authors", "book_type_subjects"} {
+			if err := tx.Exec("DELETE FROM "+joinTable+" WHERE book_type_id IN (?)", titles).Error; err != nil {
+				return err
+			}
+		}
+		result = tx.Unscoped().Where("id IN (?)", titles).Delete(&models.BookType{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged["book_types"] = result.RowsAffected
+
+		// Users once their records, fines and payments are gone, taking sessions and closed holds along
+		users := expired("users").
+			Where("NOT EXISTS (SELECT 1 FROM records WHERE records.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM fines WHERE fines.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM fine_payments WHERE fine_payments.user_id = users.id)").
+			Where("NOT EXISTS (SELECT 1 FROM holds WHERE holds.user_id = users.id AND holds.status IN (1, 2))")
+		sessions := tx.Unscoped().Model(&models.Session{}).Select("id").Where("user_id IN (?)", users)
+		if err := tx.Unscoped().Where("session_id IN (?)", sessions).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id IN (?)", users).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id IN (?)", users).Delete(&models.Hold{}).Error; err != nil {
+			return err
+		}
+		result = tx.Unscoped().Where("id IN (?)", users).Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged["users"] = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Event(context.Background(), w.Log, logging.EventPurged, "Purged deleted rows",
+		slog.Time("cutoff", cutoff), slog.Any("rows", purged))
+	return purged, nil
+}